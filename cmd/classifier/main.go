@@ -0,0 +1,68 @@
+// Command classifier trains the category classifier on labelled articles and
+// reports per-category precision and recall on a held-out split.
+//
+//	go run ./cmd/classifier -data data/news_data.json -out category_model.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"news-backend/models"
+	"news-backend/services"
+)
+
+func main() {
+	data := flag.String("data", "data/news_data.json", "labelled articles (JSON array)")
+	holdout := flag.Int("holdout", 20, "percent of articles held out for evaluation")
+	out := flag.String("out", "", "write the model trained on all labelled articles to this file")
+	flag.Parse()
+
+	articles, err := services.LoadNewsDataFromFile(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	examples := services.TrainingSet(articles)
+	if len(examples) == 0 {
+		log.Fatal("no labelled articles in ", *data)
+	}
+
+	// split by a hash of the article ID so runs are reproducible
+	var train, test []services.LabelledArticle
+	for _, ex := range examples {
+		h := fnv.New32a()
+		h.Write([]byte(ex.Article.ID))
+		if int(h.Sum32()%100) < *holdout {
+			test = append(test, ex)
+		} else {
+			train = append(train, ex)
+		}
+	}
+	fmt.Printf("labelled: %d  train: %d  test: %d  unlabelled: %d\n\n",
+		len(examples), len(train), len(test), len(articles)-len(examples))
+
+	nb := services.TrainNaiveBayes(train)
+	predict := func(a models.Article) string {
+		return nb.Predict(a)[0].Category
+	}
+	accuracy, metrics := services.EvaluateClassifier(predict, test)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "category\tsupport\tprecision\trecall\tf1\t")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%.3f\t\n", m.Category, m.Support, m.Precision, m.Recall, m.F1)
+	}
+	w.Flush()
+	fmt.Printf("\naccuracy: %.3f\n", accuracy)
+
+	if *out != "" {
+		if err := services.TrainNaiveBayes(examples).Save(*out); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("model written to", *out)
+	}
+}
@@ -0,0 +1,508 @@
+// Command newsctl imports articles into and exports them from the articles
+// collection.
+//
+//	newsctl import [flags] FILE     (FILE may be - for stdin, with -format)
+//	newsctl seed [flags] FILE       (resumable bulk load of a large archive)
+//	newsctl export [flags]
+//	newsctl migrate [status|up|down] [flags]
+//	newsctl openapi [-check] [-out FILE]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"news-backend/config"
+	"news-backend/controllers"
+	"news-backend/db"
+	"news-backend/migrations"
+	"news-backend/models"
+	"news-backend/openapi"
+	"news-backend/routes"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const usage = `usage: newsctl <command> [flags]
+
+commands:
+  import   upsert articles from a JSON, JSON Lines or CSV file
+  seed     resumable bulk load of a JSON or JSON Lines archive, with entity tagging
+  export   write stored articles as JSON, JSON Lines or CSV
+  migrate  show, apply or revert schema migrations (status, up, down)
+  openapi  print the OpenAPI spec, or check that every route is documented
+
+run "newsctl <command> -h" for the flags of a command
+`
+
+func main() {
+	_ = godotenv.Load()
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "migrate":
+		err = runMigrate(os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal("newsctl: ", err)
+	}
+}
+
+// dbFlags are shared by the commands that talk to MongoDB
+type dbFlags struct {
+	uri, db, collection string
+}
+
+func (d *dbFlags) register(fs *flag.FlagSet) {
+	cfg := config.Get().Mongo
+	fs.StringVar(&d.uri, "mongo", cfg.URI, "MongoDB URI; MONGODB_URI or the config file sets the default")
+	fs.StringVar(&d.db, "db", cfg.Database, "database name")
+	fs.StringVar(&d.collection, "collection", cfg.ArticlesCollection, "articles collection")
+}
+
+func (d *dbFlags) connect(ctx context.Context) (*mongo.Client, error) {
+	cfg := config.Get().Mongo
+	cfg.URI, cfg.Database, cfg.ArticlesCollection = d.uri, d.db, d.collection
+	cctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout.D())
+	defer cancel()
+	client, err := db.Connect(cctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Ping(cctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	services.InitArticles(client, d.db, d.collection)
+	// upserts tag entities and save them to the catalog
+	services.InitEntities(client, d.db)
+	return client, nil
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var db dbFlags
+	db.register(fs)
+	format := fs.String("format", "", "json, jsonl or csv (default from the file extension)")
+	matchBy := fs.String("match", services.MatchByID, "match existing articles by id or url (canonical URL)")
+	dryRun := fs.Bool("dry-run", false, "validate and report without writing")
+	batchSize := fs.Int("batch", 500, "articles per bulk write")
+	reportPath := fs.String("report", "", "write rejected records as JSON Lines to this file")
+	quiet := fs.Bool("quiet", false, "no progress output")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import needs exactly one input file")
+	}
+	if *matchBy != services.MatchByID && *matchBy != services.MatchByURL {
+		return fmt.Errorf("-match must be id or url")
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("-batch must be positive")
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		if path == "-" {
+			return fmt.Errorf("-format is required when reading stdin")
+		}
+		f, err := services.FormatFromPath(path)
+		if err != nil {
+			return err
+		}
+		*format = f
+	}
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	reader, err := services.NewArticleReader(in, *format)
+	if err != nil {
+		return err
+	}
+
+	var report *json.Encoder
+	if *reportPath != "" {
+		f, err := os.Create(*reportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		report = json.NewEncoder(f)
+	}
+
+	ctx := context.Background()
+	if !*dryRun {
+		client, err := db.connect(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.Background())
+	}
+
+	var (
+		read, invalid int
+		total         services.ImportResult
+		batch         []models.Article
+		inBatch       = map[string]bool{}
+		shown         int
+		start         = time.Now()
+	)
+	progress := func(final bool) {
+		if *quiet {
+			return
+		}
+		end := "\r"
+		if final {
+			end = "\n"
+		}
+		written := ""
+		if !*dryRun {
+			written = fmt.Sprintf("  inserted %d  updated %d", total.Inserted, total.Updated)
+		}
+		fmt.Fprintf(os.Stderr, "read %d  invalid %d%s  (%.0f/s)%s",
+			read, invalid, written, float64(read)/time.Since(start).Seconds(), end)
+	}
+	reject := func(e *services.RecordError) error {
+		invalid++
+		if report != nil {
+			return report.Encode(e)
+		}
+		// without a report file show the first few on stderr
+		if shown < 20 {
+			shown++
+			fmt.Fprintf(os.Stderr, "\r%s\n", e.Error())
+		}
+		return nil
+	}
+	flush := func() error {
+		if len(batch) > 0 && !*dryRun {
+			res, err := services.UpsertArticles(ctx, batch, *matchBy)
+			total.Add(res)
+			if err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		inBatch = map[string]bool{}
+		progress(false)
+		return nil
+	}
+
+	for {
+		a, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if rerr, ok := err.(*services.RecordError); ok {
+			read++
+			if err := reject(rerr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("after record %d: %w", read, err)
+		}
+		read++
+		if problems := services.ValidateArticle(a); len(problems) > 0 {
+			if err := reject(&services.RecordError{Record: read, ID: a.ID, URL: a.URL, Errors: problems}); err != nil {
+				return err
+			}
+			continue
+		}
+		services.PrepareForImport(&a)
+		key := a.ID
+		if *matchBy == services.MatchByURL {
+			key = a.CanonicalURL
+		}
+		// unordered bulk upserts of the same key could insert twice, so a
+		// repeated key starts a new batch
+		if inBatch[key] {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		batch = append(batch, a)
+		inBatch[key] = true
+		if len(batch) >= *batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	progress(true)
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "dry run: %d valid, %d invalid, nothing written\n", read-invalid, invalid)
+	}
+	if invalid > 0 && report == nil && invalid > shown {
+		fmt.Fprintf(os.Stderr, "%d more invalid records; use -report to list them all\n", invalid-shown)
+	}
+	return nil
+}
+
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	var db dbFlags
+	db.register(fs)
+	batchSize := fs.Int("batch", config.Get().Data.SeedBatchSize, "articles per bulk upsert")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("seed needs exactly one input file")
+	}
+	ctx := context.Background()
+	client, err := db.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	start := time.Now()
+	report, err := services.SeedArticles(ctx, fs.Arg(0), *batchSize)
+	if report.UpToDate {
+		fmt.Fprintln(os.Stderr, "already seeded; touch the file to load it again")
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "read %d (resumed after %d)  inserted %d  updated %d  skipped %d  in %s\n",
+		report.Read, report.Resumed, report.Inserted, report.Updated, report.Skipped, time.Since(start).Round(time.Second))
+	for reason, n := range report.Reasons {
+		fmt.Fprintf(os.Stderr, "  %6d  %s\n", n, reason)
+	}
+	return err
+}
+
+func runMigrate(args []string) error {
+	action := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var db dbFlags
+	db.register(fs)
+	to := fs.Int("to", -1, "target version: up applies through it (default latest), down reverts above it (default one step)")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	client, err := db.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+	database := client.Database(db.db)
+
+	var ran []int
+	switch action {
+	case "status":
+		list, err := migrations.List(ctx, database)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			state := "pending"
+			if m.AppliedAt != nil {
+				state = "applied " + m.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-28s %s\n", m.Version, m.Name, state)
+		}
+		return nil
+	case "up":
+		target := *to
+		if target < 0 {
+			target = 0
+		}
+		ran, err = migrations.Up(ctx, database, db.collection, target)
+	case "down":
+		target := *to
+		if target < 0 {
+			// one step: revert the newest applied migration
+			list, err := migrations.List(ctx, database)
+			if err != nil {
+				return err
+			}
+			target = 0
+			for i := len(list) - 1; i >= 0; i-- {
+				if list[i].AppliedAt != nil {
+					for j := i - 1; j >= 0; j-- {
+						if list[j].AppliedAt != nil {
+							target = list[j].Version
+							break
+						}
+					}
+					break
+				}
+			}
+		}
+		ran, err = migrations.Down(ctx, database, db.collection, target)
+	default:
+		return fmt.Errorf("unknown migrate action %q (want status, up or down)", action)
+	}
+	if len(ran) == 0 && err == nil {
+		fmt.Fprintln(os.Stderr, "nothing to do")
+	}
+	for _, v := range ran {
+		fmt.Fprintf(os.Stderr, "%s %d\n", action, v)
+	}
+	return err
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var db dbFlags
+	db.register(fs)
+	format := fs.String("format", "", "json, jsonl or csv (default from -out, else json)")
+	outPath := fs.String("out", "-", "output file, - for stdout")
+	category := fs.String("category", "", "only articles in this category")
+	source := fs.String("source", "", "only articles from this source")
+	lang := fs.String("lang", "", "only articles in this language")
+	limit := fs.Int64("limit", 0, "maximum articles to export (0 for all)")
+	quiet := fs.Bool("quiet", false, "no progress output")
+	fs.Parse(args)
+
+	if *format == "" {
+		*format = services.FormatJSON
+		if *outPath != "-" {
+			f, err := services.FormatFromPath(*outPath)
+			if err != nil {
+				return err
+			}
+			*format = f
+		}
+	}
+	out := os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w, err := services.NewArticleWriter(out, *format)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, err := db.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	filter := bson.M{}
+	if *category != "" {
+		filter["category"] = *category
+	}
+	if *source != "" {
+		filter["source_name"] = *source
+	}
+	if *lang != "" {
+		filter["lang"] = *lang
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "publication_date", Value: -1}})
+	if *limit > 0 {
+		opts.SetLimit(*limit)
+	}
+	cur, err := client.Database(db.db).Collection(db.collection).Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	n := 0
+	for cur.Next(ctx) {
+		var a models.Article
+		if err := cur.Decode(&a); err != nil {
+			return err
+		}
+		if err := w.Write(a); err != nil {
+			return err
+		}
+		n++
+		if !*quiet && n%1000 == 0 {
+			fmt.Fprintf(os.Stderr, "exported %d\r", n)
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, "exported %d articles\n", n)
+	}
+	return nil
+}
+
+// runOpenAPI builds the server's router without starting it and prints the
+// spec generated from it. With -check it fails when a route has no spec
+// entry, for use in CI.
+func runOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+	check := fs.Bool("check", false, "only verify that every route is documented")
+	outPath := fs.String("out", "-", "output file, - for stdout")
+	fs.Parse(args)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := routes.NewRouter()
+	if *check {
+		missing := openapi.Missing(router.Routes(), controllers.Operations)
+		for _, m := range missing {
+			fmt.Fprintln(os.Stderr, "undocumented route:", m)
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%d routes missing from the OpenAPI spec", len(missing))
+		}
+		fmt.Fprintf(os.Stderr, "all %d routes documented\n", len(router.Routes()))
+		return nil
+	}
+	out := os.Stdout
+	if *outPath != "-" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(controllers.BuildOpenAPI(router))
+}
@@ -0,0 +1,572 @@
+// Package config holds the typed server configuration. Values come from
+// defaults, then an optional JSON or YAML file (CONFIG_FILE or -config), then
+// environment variables, then command-line flags, each overriding the last.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config is the effective configuration. Fields tagged env can be set from
+// that environment variable or from the flag of the same name in kebab case
+// (MONGODB_URI -> -mongodb-uri). Fields tagged secret are redacted when shown.
+type Config struct {
+	Server     ServerConfig     `json:"server"`
+	Mongo      MongoConfig      `json:"mongo"`
+	Data       DataConfig       `json:"data"`
+	Cache      CacheConfig      `json:"cache"`
+	Trending   TrendingConfig   `json:"trending"`
+	LLM        LLMConfig        `json:"llm"`
+	Embeddings EmbeddingsConfig `json:"embeddings"`
+	Auth       AuthConfig       `json:"auth"`
+	RateLimit  RateLimitConfig  `json:"rate_limit"`
+	Tracing    TracingConfig    `json:"tracing"`
+	Log        LogConfig        `json:"log"`
+	Health     HealthConfig     `json:"health"`
+	Live       LiveConfig       `json:"live"`
+}
+
+type ServerConfig struct {
+	Addr            string   `json:"addr" env:"ADDR"`
+	Mode            string   `json:"mode" env:"GIN_MODE"`
+	ReadTimeout     Duration `json:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout    Duration `json:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout     Duration `json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout Duration `json:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+type MongoConfig struct {
+	URI                string   `json:"uri" env:"MONGODB_URI" secret:"url"`
+	Database           string   `json:"database" env:"MONGODB_DATABASE"`
+	ArticlesCollection string   `json:"articles_collection" env:"MONGODB_ARTICLES_COLLECTION"`
+	ConnectTimeout     Duration `json:"connect_timeout" env:"MONGODB_CONNECT_TIMEOUT"`
+	MigrateOnStartup   bool     `json:"migrate_on_startup" env:"MIGRATE_ON_STARTUP"`
+	// connection attempts back off from RetryInitial, doubling up to RetryMax
+	RetryInitial Duration `json:"retry_initial" env:"MONGODB_RETRY_INITIAL"`
+	RetryMax     Duration `json:"retry_max" env:"MONGODB_RETRY_MAX"`
+	// how often the connection is pinged once up
+	MonitorInterval Duration `json:"monitor_interval" env:"MONGODB_MONITOR_INTERVAL"`
+	// connection pool of the one client shared by the whole process
+	MaxPoolSize     int      `json:"max_pool_size" env:"MONGODB_MAX_POOL_SIZE"`
+	MinPoolSize     int      `json:"min_pool_size" env:"MONGODB_MIN_POOL_SIZE"`
+	MaxConnIdleTime Duration `json:"max_conn_idle_time" env:"MONGODB_MAX_CONN_IDLE_TIME"`
+	// how long an operation waits for a usable server
+	ServerSelectionTimeout Duration `json:"server_selection_timeout" env:"MONGODB_SERVER_SELECTION_TIMEOUT"`
+	// bounds operations whose context has no deadline; 0 leaves them unbounded
+	OperationTimeout Duration `json:"operation_timeout" env:"MONGODB_OPERATION_TIMEOUT"`
+}
+
+type DataConfig struct {
+	SeedFile          string `json:"seed_file" env:"SEED_FILE"`
+	SeedBatchSize     int    `json:"seed_batch_size" env:"SEED_BATCH_SIZE"`
+	CategoryModelPath string `json:"category_model_path" env:"CATEGORY_MODEL_PATH"`
+}
+
+type CacheConfig struct {
+	TrendingTTL       Duration `json:"trending_ttl" env:"CACHE_TRENDING_TTL"`
+	APIKeyTTL         Duration `json:"api_key_ttl" env:"CACHE_API_KEY_TTL"`
+	TFIDFRebuildAfter Duration `json:"tfidf_rebuild_after" env:"CACHE_TFIDF_REBUILD_AFTER"`
+	EntitiesTTL       Duration `json:"entities_ttl" env:"CACHE_ENTITIES_TTL"`
+	RelevanceRefresh  Duration `json:"relevance_refresh" env:"RELEVANCE_REFRESH_INTERVAL"`
+}
+
+type TrendingConfig struct {
+	HalfLife       Duration `json:"half_life" env:"TRENDING_HALF_LIFE"`
+	DefaultRadius  float64  `json:"default_radius_km" env:"TRENDING_DEFAULT_RADIUS_KM"`
+	EventRetention Duration `json:"event_retention" env:"TRENDING_EVENT_RETENTION"`
+	Simulate       bool     `json:"simulate" env:"TRENDING_SIMULATE"`
+}
+
+type LLMConfig struct {
+	APIURL  string   `json:"api_url" env:"LLM_API_URL"`
+	APIKey  string   `json:"api_key" env:"LLM_API_KEY" secret:"true"`
+	Model   string   `json:"model" env:"LLM_MODEL"`
+	Timeout Duration `json:"timeout" env:"LLM_TIMEOUT"`
+}
+
+type EmbeddingsConfig struct {
+	APIURL string `json:"api_url" env:"EMBEDDINGS_API_URL"`
+	APIKey string `json:"api_key" env:"EMBEDDINGS_API_KEY" secret:"true"`
+	Model  string `json:"model" env:"EMBEDDINGS_MODEL"`
+}
+
+type AuthConfig struct {
+	BootstrapAdminKey string `json:"bootstrap_admin_key" env:"AUTH_BOOTSTRAP_ADMIN_KEY" secret:"true"`
+	JWTHS256Secret    string `json:"jwt_hs256_secret" env:"JWT_HS256_SECRET" secret:"true"`
+	JWTRS256PublicKey string `json:"jwt_rs256_public_key" env:"JWT_RS256_PUBLIC_KEY"`
+	JWTIssuer         string `json:"jwt_issuer" env:"JWT_ISSUER"`
+	JWTAudience       string `json:"jwt_audience" env:"JWT_AUDIENCE"`
+}
+
+type RateLimitConfig struct {
+	RedisURL string `json:"redis_url" env:"RATE_LIMIT_REDIS_URL" secret:"url"`
+}
+
+type TracingConfig struct {
+	Exporter     string  `json:"exporter" env:"OTEL_TRACES_EXPORTER"` // none, stdout or otlp
+	OTLPEndpoint string  `json:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName  string  `json:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio  float64 `json:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type LogConfig struct {
+	Level  string `json:"level" env:"LOG_LEVEL"`   // debug, info, warn or error
+	Format string `json:"format" env:"LOG_FORMAT"` // json or text
+}
+
+type HealthConfig struct {
+	CheckTimeout     Duration `json:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	LLMCheckInterval Duration `json:"llm_check_interval" env:"HEALTH_LLM_CHECK_INTERVAL"`
+	// readiness fails when the newest stored article is older than this; 0 only reports the lag
+	MaxIngestionLag Duration `json:"max_ingestion_lag" env:"HEALTH_MAX_INGESTION_LAG"`
+}
+
+type LiveConfig struct {
+	// comma-separated browser origins (https://app.example.com) allowed to open
+	// the live feed besides the API's own; "*" allows any. Clients that send
+	// no Origin, like mobile apps, are always allowed.
+	AllowedOrigins string `json:"allowed_origins" env:"LIVE_ALLOWED_ORIGINS"`
+	// how often articles stored by other processes are picked up
+	ImportPollInterval Duration `json:"import_poll_interval" env:"LIVE_IMPORT_POLL_INTERVAL"`
+}
+
+// Defaults returns the configuration used when nothing overrides it
+func Defaults() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			Mode:            "debug",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(2 * time.Minute),
+			ShutdownTimeout: Duration(5 * time.Second),
+		},
+		Mongo: MongoConfig{
+			URI:                    "mongodb://localhost:27017",
+			Database:               "news",
+			ArticlesCollection:     "articles",
+			ConnectTimeout:         Duration(10 * time.Second),
+			MigrateOnStartup:       true,
+			RetryInitial:           Duration(time.Second),
+			RetryMax:               Duration(30 * time.Second),
+			MonitorInterval:        Duration(5 * time.Second),
+			MaxPoolSize:            100,
+			MaxConnIdleTime:        Duration(5 * time.Minute),
+			ServerSelectionTimeout: Duration(10 * time.Second),
+		},
+		Data: DataConfig{
+			SeedFile:      "data/news_data.json",
+			SeedBatchSize: 1000,
+		},
+		Cache: CacheConfig{
+			TrendingTTL:       Duration(60 * time.Second),
+			APIKeyTTL:         Duration(30 * time.Second),
+			TFIDFRebuildAfter: Duration(5 * time.Minute),
+			EntitiesTTL:       Duration(60 * time.Second),
+			RelevanceRefresh:  Duration(10 * time.Minute),
+		},
+		Trending: TrendingConfig{
+			HalfLife:       Duration(12 * time.Hour),
+			DefaultRadius:  50,
+			EventRetention: Duration(48 * time.Hour),
+			Simulate:       true,
+		},
+		LLM: LLMConfig{
+			Model:   "gpt-4o-mini",
+			Timeout: Duration(20 * time.Second),
+		},
+		Embeddings: EmbeddingsConfig{
+			Model: "text-embedding-3-small",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318",
+			ServiceName:  "news-backend",
+			SampleRatio:  1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Health: HealthConfig{
+			CheckTimeout:     Duration(2 * time.Second),
+			LLMCheckInterval: Duration(30 * time.Second),
+		},
+		Live: LiveConfig{
+			ImportPollInterval: Duration(5 * time.Second),
+		},
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	current *Config
+)
+
+// Get returns the loaded configuration. Callers that run before Load (the
+// command-line tools, for instance) get defaults, the config file and the
+// environment.
+func Get() *Config {
+	mu.RLock()
+	c := current
+	mu.RUnlock()
+	if c != nil {
+		return c
+	}
+	loaded, err := Load(nil)
+	if err != nil {
+		// fall back to defaults rather than failing deep inside a library call
+		d := Defaults()
+		loaded = &d
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = loaded
+	}
+	return current
+}
+
+// Load builds and validates the configuration from args (without the program
+// name), the environment and the config file, and makes it the one returned
+// by Get. Unknown flags are an error.
+func Load(args []string) (*Config, error) {
+	c := Defaults()
+
+	fs := flag.NewFlagSet("news-backend", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON or YAML config file")
+	flagValues := registerFlags(fs, &c)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *file != "" {
+		if err := loadFile(*file, &c); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&c); err != nil {
+		return nil, err
+	}
+	// flags last: only the ones given on the command line
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if setter, ok := flagValues[f.Name]; ok && flagErr == nil {
+			flagErr = setter(f.Value.String())
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	current = &c
+	mu.Unlock()
+	return &c, nil
+}
+
+func loadFile(path string, c *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, c)
+	default:
+		dec := json.NewDecoder(strings.NewReader(string(b)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(c)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// field is a settable leaf of Config with its tags
+type field struct {
+	value  reflect.Value
+	env    string
+	secret string
+	path   string
+}
+
+func fields(c *Config) []field {
+	out := []field{}
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name := strings.Split(sf.Tag.Get("json"), ",")[0]
+			fv := v.Field(i)
+			if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(Duration(0)) {
+				walk(fv, prefix+name+".")
+				continue
+			}
+			out = append(out, field{value: fv, env: sf.Tag.Get("env"), secret: sf.Tag.Get("secret"), path: prefix + name})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return out
+}
+
+// set parses s into the field according to its type
+func (f field) set(s string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(s)
+	case Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		f.value.Set(reflect.ValueOf(Duration(d)))
+	case int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		f.value.SetInt(int64(n))
+	case float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		f.value.SetFloat(x)
+	case bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("%s: unsupported type %s", f.path, f.value.Type())
+	}
+	return nil
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// registerFlags adds a flag of the field's type for every env-tagged field, so
+// a bare -trending-simulate means true, and returns the setters that apply a
+// flag's value to its field
+func registerFlags(fs *flag.FlagSet, c *Config) map[string]func(string) error {
+	setters := map[string]func(string) error{}
+	for _, f := range fields(c) {
+		if f.env == "" {
+			continue
+		}
+		name := flagName(f.env)
+		usage := fmt.Sprintf("%s (env %s)", f.path, f.env)
+		switch f.value.Interface().(type) {
+		case bool:
+			fs.Bool(name, false, usage)
+		case int:
+			fs.Int(name, 0, usage)
+		case float64:
+			fs.Float64(name, 0, usage)
+		case Duration:
+			fs.Duration(name, 0, usage)
+		default:
+			fs.String(name, "", usage)
+		}
+		setters[name] = f.set
+	}
+	return setters
+}
+
+func applyEnv(c *Config) error {
+	// the Dockerfile and most platforms set PORT rather than a listen address
+	if port := os.Getenv("PORT"); port != "" && os.Getenv("ADDR") == "" {
+		c.Server.Addr = ":" + port
+	}
+	for _, f := range fields(c) {
+		if f.env == "" {
+			continue
+		}
+		if v, ok := os.LookupEnv(f.env); ok && v != "" {
+			if err := f.set(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	bad := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if c.Server.Addr == "" {
+		bad("server.addr is required")
+	}
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		bad("server.mode must be debug, release or test")
+	}
+	durations := []struct {
+		name string
+		d    Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"mongo.connect_timeout", c.Mongo.ConnectTimeout},
+		{"mongo.retry_initial", c.Mongo.RetryInitial},
+		{"mongo.retry_max", c.Mongo.RetryMax},
+		{"mongo.monitor_interval", c.Mongo.MonitorInterval},
+		{"mongo.max_conn_idle_time", c.Mongo.MaxConnIdleTime},
+		{"mongo.server_selection_timeout", c.Mongo.ServerSelectionTimeout},
+		{"cache.trending_ttl", c.Cache.TrendingTTL},
+		{"cache.api_key_ttl", c.Cache.APIKeyTTL},
+		{"cache.tfidf_rebuild_after", c.Cache.TFIDFRebuildAfter},
+		{"cache.entities_ttl", c.Cache.EntitiesTTL},
+		{"cache.relevance_refresh", c.Cache.RelevanceRefresh},
+		{"trending.half_life", c.Trending.HalfLife},
+		{"trending.event_retention", c.Trending.EventRetention},
+		{"llm.timeout", c.LLM.Timeout},
+		{"health.check_timeout", c.Health.CheckTimeout},
+		{"health.llm_check_interval", c.Health.LLMCheckInterval},
+		{"live.import_poll_interval", c.Live.ImportPollInterval},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			bad("%s must be positive", d.name)
+		}
+	}
+	if u, err := url.Parse(c.Mongo.URI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		bad("mongo.uri must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if c.Mongo.Database == "" || c.Mongo.ArticlesCollection == "" {
+		bad("mongo.database and mongo.articles_collection are required")
+	}
+	if c.Mongo.MaxPoolSize < 1 || c.Mongo.MinPoolSize < 0 || c.Mongo.MinPoolSize > c.Mongo.MaxPoolSize {
+		bad("mongo pool sizes must satisfy 0 <= min_pool_size <= max_pool_size, max_pool_size >= 1")
+	}
+	if c.Mongo.OperationTimeout < 0 {
+		bad("mongo.operation_timeout must not be negative")
+	}
+	if c.Health.MaxIngestionLag < 0 {
+		bad("health.max_ingestion_lag must not be negative")
+	}
+	if c.Data.SeedBatchSize < 1 {
+		bad("data.seed_batch_size must be at least 1")
+	}
+	if c.Trending.DefaultRadius <= 0 {
+		bad("trending.default_radius_km must be positive")
+	}
+	for _, u := range []struct{ name, raw string }{{"llm.api_url", c.LLM.APIURL}, {"embeddings.api_url", c.Embeddings.APIURL}} {
+		if u.raw == "" {
+			continue
+		}
+		if p, err := url.Parse(u.raw); err != nil || (p.Scheme != "http" && p.Scheme != "https") || p.Host == "" {
+			bad("%s must be an http(s) URL", u.name)
+		}
+	}
+	if c.RateLimit.RedisURL != "" {
+		if u, err := url.Parse(c.RateLimit.RedisURL); err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			bad("rate_limit.redis_url must be a redis:// or rediss:// URL")
+		}
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		bad("tracing.exporter must be none, stdout or otlp")
+	}
+	if c.Tracing.Exporter == "otlp" {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			bad("tracing.otlp_endpoint must be an http(s) URL")
+		}
+	}
+	for _, o := range strings.Split(c.Live.AllowedOrigins, ",") {
+		if o = strings.TrimSpace(o); o == "" || o == "*" {
+			continue
+		}
+		if u, err := url.Parse(o); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			bad("live.allowed_origins must be http(s) origins or *, got %q", o)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		bad("tracing.sample_ratio must be between 0 and 1")
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		bad("log.level must be debug, info, warn or error")
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		bad("log.format must be json or text")
+	}
+	return errors.Join(errs...)
+}
+
+const redacted = "REDACTED"
+
+// Redacted returns a copy safe to show: secrets are replaced and passwords
+// are removed from URLs
+func (c *Config) Redacted() Config {
+	out := *c
+	for _, f := range fields(&out) {
+		s, ok := f.value.Interface().(string)
+		if !ok || s == "" {
+			continue
+		}
+		switch f.secret {
+		case "true":
+			f.value.SetString(redacted)
+		case "url":
+			if u, err := url.Parse(s); err == nil {
+				f.value.SetString(u.Redacted())
+			} else {
+				f.value.SetString(redacted)
+			}
+		}
+	}
+	return out
+}
+
+// Duration is a time.Duration written as a string like "30s" in files and output
+type Duration time.Duration
+
+// D returns the value as a time.Duration
+func (d Duration) D() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+
+	"news-backend/models"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relatedArticle adds the similarity score to a response article
+type relatedArticle struct {
+	responseArticle
+	RelatedScore float64 `json:"related_score"`
+}
+
+type relatedQuery struct {
+	Limit int `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/articles/:id/related?limit=5&lang=hi
+func GetRelatedArticles(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	var q relatedQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	limit := q.Limit
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
+
+	articles, err := fetchAllArticles(ctx)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	var target *models.Article
+	for i := range articles {
+		if articles[i].ID == id {
+			target = &articles[i]
+			break
+		}
+	}
+	if target == nil {
+		notFound(c, "article")
+		return
+	}
+	// rank against the whole corpus so the cached index is reused, then filter
+	n := limit
+	if lang != "" {
+		n = len(articles)
+	}
+	list, scores := []responseArticle{}, []float64{}
+	for _, r := range services.RelatedArticles(*target, articles, n) {
+		if len(list) >= limit {
+			break
+		}
+		if lang != "" && articleLang(r.Article) != lang {
+			continue
+		}
+		list = append(list, toResponseArticle(r.Article, nil))
+		scores = append(scores, r.Score)
+	}
+	decorateArticles(c, list)
+	resp := make([]relatedArticle, len(list))
+	for i := range list {
+		resp[i] = relatedArticle{responseArticle: list[i], RelatedScore: scores[i]}
+	}
+	c.JSON(http.StatusOK, gin.H{"article_id": id, "articles": resp})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Role string `json:"role" binding:"omitempty,oneof=reader editor admin"`
+}
+
+// POST /api/v1/admin/keys
+func CreateAPIKey(c *gin.Context) {
+	var req createKeyRequest
+	if !bindJSON(c, &req) || !requireText(c, "name", req.Name) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Role == "" {
+		req.Role = services.RoleReader
+	}
+	plain, key, err := services.CreateAPIKey(c.Request.Context(), req.Name, req.Role)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	// the plaintext key is only ever returned here
+	c.JSON(http.StatusCreated, gin.H{"key": plain, "api_key": key})
+}
+
+// GET /api/v1/admin/keys
+func ListAPIKeys(c *gin.Context) {
+	keys, err := services.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys, "total": len(keys)})
+}
+
+// DELETE /api/v1/admin/keys/:id
+func RevokeAPIKey(c *gin.Context) {
+	err := services.RevokeAPIKey(c.Request.Context(), c.Param("id"))
+	if err == services.ErrKeyNotFound {
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"net/http"
+
+	"news-backend/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /api/v1/admin/config
+// the effective configuration with secrets redacted
+func GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, config.Get().Redacted())
+}
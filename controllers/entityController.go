@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http"
+
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type entitiesQuery struct {
+	Type  string `form:"type" binding:"omitempty,oneof=person organization place other"`
+	Limit int    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+type entityQuery struct {
+	Limit int `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+type entityArticlesQuery struct {
+	Limit  int `form:"limit,default=10" binding:"min=1,max=100"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+// GET /api/v1/news/entities?type=person&limit=20
+func ListEntities(c *gin.Context) {
+	var q entitiesQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	list, err := services.TopEntities(c.Request.Context(), q.Type, q.Limit)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entities": list})
+}
+
+// GET /api/v1/news/entities/:id?limit=10
+// returns the entity and the entities most often mentioned with it
+func GetEntity(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	var q entityQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	e, err := services.GetEntity(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		notFound(c, "entity")
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	related, err := services.CoOccurringEntities(ctx, id, q.Limit)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entity": e, "co_occurring": related})
+}
+
+// GET /api/v1/news/entities/:id/articles?limit=10&offset=0&lang=hi
+func GetEntityArticles(c *gin.Context) {
+	ctx := c.Request.Context()
+	var q entityArticlesQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
+	articles, total, err := services.EntityArticles(ctx, c.Param("id"), lang, q.Offset, q.Limit)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	resp := []responseArticle{}
+	for _, a := range articles {
+		resp = append(resp, toResponseArticle(a, nil))
+	}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": total, "offset": q.Offset})
+}
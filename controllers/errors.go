@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// bindQuery binds and validates query parameters into req, a pointer to a
+// struct with form and binding tags. It writes a validation problem and
+// returns false when they are invalid.
+func bindQuery(c *gin.Context, req interface{}) bool {
+	if missing := emptyRequired(c, req); len(missing) > 0 {
+		problem.Invalid(c, missing...)
+		return false
+	}
+	err := c.ShouldBindQuery(req)
+	if err == nil {
+		return true
+	}
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		problem.Invalid(c, problem.BindError(err)...)
+		return false
+	}
+	// gin stops at the first value it cannot parse without naming it
+	problem.Invalid(c, unparsableQuery(c, req)...)
+	return false
+}
+
+// bindJSON binds and validates a JSON body into req like bindQuery
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		problem.Invalid(c, problem.BindError(err)...)
+		return false
+	}
+	return true
+}
+
+// emptyRequired names the required query parameters that are absent or empty.
+// gin binds ?lat= to a pointer to zero, which the required binding accepts.
+func emptyRequired(c *gin.Context, req interface{}) []problem.FieldError {
+	out := []problem.FieldError{}
+	t := reflect.TypeOf(req).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if name == "" || !slices.Contains(strings.Split(f.Tag.Get("binding"), ","), "required") {
+			continue
+		}
+		if strings.TrimSpace(c.Query(name)) == "" {
+			out = append(out, problem.FieldError{Field: name, Code: "required", Message: "is required"})
+		}
+	}
+	return out
+}
+
+// unparsableQuery names the numeric query parameters whose values are not numbers
+func unparsableQuery(c *gin.Context, req interface{}) []problem.FieldError {
+	out := []problem.FieldError{}
+	t := reflect.TypeOf(req).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		raw := c.Query(name)
+		if name == "" || raw == "" {
+			continue
+		}
+		kind := f.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = f.Type.Elem().Kind()
+		}
+		switch kind {
+		case reflect.Int, reflect.Int64:
+			if _, err := strconv.Atoi(raw); err != nil {
+				out = append(out, problem.FieldError{Field: name, Code: "type", Message: "must be an integer"})
+			}
+		case reflect.Float64:
+			if _, err := strconv.ParseFloat(raw, 64); err != nil {
+				out = append(out, problem.FieldError{Field: name, Code: "type", Message: "must be a number"})
+			}
+		case reflect.Bool:
+			if _, err := strconv.ParseBool(raw); err != nil {
+				out = append(out, problem.FieldError{Field: name, Code: "type", Message: "must be true or false"})
+			}
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, problem.FieldError{Code: "invalid", Message: "query parameters could not be parsed"})
+	}
+	return out
+}
+
+// requireText rejects a required text field that is only whitespace, which
+// the required binding accepts
+func requireText(c *gin.Context, field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		problem.Invalid(c, problem.FieldError{Field: field, Code: "required", Message: "is required"})
+		return false
+	}
+	return true
+}
+
+// serverError answers for an unexpected error: 503 while the database is
+// unavailable, otherwise a logged 500 that does not expose the cause
+func serverError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrDatabaseUnavailable) {
+		problem.Unavailable(c, 30, "database unavailable")
+		return
+	}
+	problem.Internal(c, err)
+}
+
+// notFound answers 404 for the named resource
+func notFound(c *gin.Context, what string) {
+	problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, what+" not found")
+}
@@ -0,0 +1,426 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/middleware"
+	"news-backend/models"
+	"news-backend/problem"
+	"news-backend/services"
+	"news-backend/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// graphQLRequest is a POST body as sent by GraphQL clients
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphQLQuery is the GET form of graphQLRequest; variables are JSON encoded
+type graphQLQuery struct {
+	Query         string `form:"query" binding:"required"`
+	OperationName string `form:"operationName"`
+	Variables     string `form:"variables"`
+}
+
+// graphQLResponse is the GraphQL response body
+type graphQLResponse struct {
+	Data   map[string]interface{}     `json:"data"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// GET /graphql?query={...}&variables={...}
+// POST /graphql
+// runs a GraphQL query over articles, sources, categories and trending
+func GraphQL(c *gin.Context) {
+	var req graphQLRequest
+	if c.Request.Method == http.MethodGet {
+		var q graphQLQuery
+		if !bindQuery(c, &q) {
+			return
+		}
+		req.Query, req.OperationName = q.Query, q.OperationName
+		if q.Variables != "" {
+			if err := json.Unmarshal([]byte(q.Variables), &req.Variables); err != nil {
+				problem.Invalid(c, problem.FieldError{Field: "variables", Code: "malformed", Message: "is not a JSON object"})
+				return
+			}
+		}
+	} else if !bindJSON(c, &req) {
+		return
+	}
+	if !requireText(c, "query", req.Query) {
+		return
+	}
+
+	ctx, span := tracing.Start(c.Request.Context(), "graphql.execute", attribute.String("graphql.operation", req.OperationName))
+	principal, _ := middleware.CurrentPrincipal(c)
+	ctx = withGraphQLState(ctx, &graphQLState{
+		principal: principal,
+		loader:    newArticleLoader(ctx),
+		lang:      services.PreferredLanguage(c.GetHeader("Accept-Language")),
+	})
+	res := graphql.Do(graphql.Params{
+		Schema:         graphQLSchema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+	span.SetAttributes(attribute.Int("graphql.errors", len(res.Errors)))
+	span.End()
+
+	out := graphQLResponse{Errors: res.Errors}
+	out.Data, _ = res.Data.(map[string]interface{})
+	for i := range out.Errors {
+		if out.Errors[i].Extensions == nil {
+			out.Errors[i].Extensions = errorExtensions(out.Errors[i])
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// graphQLState is what resolvers share within one request
+type graphQLState struct {
+	principal services.Principal
+	loader    *articleLoader
+	// lang is the Accept-Language preference for summaries
+	lang string
+}
+
+type graphQLStateKey struct{}
+
+func withGraphQLState(ctx context.Context, s *graphQLState) context.Context {
+	return context.WithValue(ctx, graphQLStateKey{}, s)
+}
+
+func stateFrom(ctx context.Context) *graphQLState {
+	s, _ := ctx.Value(graphQLStateKey{}).(*graphQLState)
+	return s
+}
+
+// graphQLError is a resolver error carrying a problem code in its
+// extensions, so clients branch on the same codes as for REST errors
+type graphQLError struct {
+	message string
+	code    string
+	field   string
+}
+
+func (e *graphQLError) Error() string { return e.message }
+
+func (e *graphQLError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.code}
+	if e.field != "" {
+		ext["field"] = e.field
+	}
+	return ext
+}
+
+// invalidArg rejects an argument like a REST validation failure
+func invalidArg(field, message string) error {
+	return &graphQLError{message: field + " " + message, code: problem.CodeValidation, field: field}
+}
+
+// resolverError answers for an unexpected error like serverError: unavailable
+// while the database is down, otherwise a logged internal error that does
+// not expose the cause
+func resolverError(ctx context.Context, err error) error {
+	var ge *graphQLError
+	if errors.As(err, &ge) {
+		return ge
+	}
+	if errors.Is(err, services.ErrDatabaseUnavailable) {
+		return &graphQLError{message: "database unavailable", code: problem.CodeUnavailable}
+	}
+	slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+	return &graphQLError{message: "the request could not be completed", code: problem.CodeInternal}
+}
+
+// errorExtensions recovers the code of an error whose extensions graphql-go
+// dropped, as it does for errors returned from thunks. Errors without one
+// are in the query document itself.
+func errorExtensions(fe gqlerrors.FormattedError) map[string]interface{} {
+	var err error = fe
+	for err != nil {
+		switch e := err.(type) {
+		case *graphQLError:
+			return e.Extensions()
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			err = nil
+		}
+	}
+	if len(fe.Path) == 0 {
+		return map[string]interface{}{"code": problem.CodeValidation}
+	}
+	return map[string]interface{}{"code": problem.CodeInternal}
+}
+
+// related lookups each rank the whole corpus, so one request may only make
+// this many
+const maxRelatedPerRequest = 100
+
+// articleLoader reads articles for one request. The corpus is fetched at most
+// once however many fields need it, and lookups by ID made while one level of
+// the query resolves are answered by a single query, so nested fields do not
+// cost a Mongo round trip each.
+type articleLoader struct {
+	ctx context.Context
+
+	corpusOnce sync.Once
+	corpus     []models.Article
+	corpusErr  error
+
+	catalogOnce sync.Once
+	sources     map[string]*sourceNode
+	categories  map[string]*categoryNode
+
+	mu      sync.Mutex
+	batch   *idBatch
+	byID    map[string]models.Article
+	related int
+}
+
+// idBatch is the IDs requested before the first of them is needed
+type idBatch struct {
+	ids   []string
+	once  sync.Once
+	found map[string]models.Article
+	err   error
+}
+
+func newArticleLoader(ctx context.Context) *articleLoader {
+	return &articleLoader{ctx: ctx, byID: map[string]models.Article{}}
+}
+
+// Corpus returns every article, read once per request
+func (l *articleLoader) Corpus() ([]models.Article, error) {
+	l.corpusOnce.Do(func() {
+		corpus, err := fetchAllArticles(l.ctx)
+		l.mu.Lock()
+		l.corpus, l.corpusErr = corpus, err
+		l.mu.Unlock()
+	})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.corpus, l.corpusErr
+}
+
+// Load queues id for the next batch and returns a thunk resolving to the
+// article, or nil when there is none. graphql-go calls the thunks of a level
+// after resolving all of its fields, so the first call fetches the batch.
+func (l *articleLoader) Load(id string) func() (interface{}, error) {
+	l.mu.Lock()
+	if l.batch == nil {
+		l.batch = &idBatch{}
+	}
+	b := l.batch
+	b.ids = append(b.ids, id)
+	l.mu.Unlock()
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			if l.batch == b {
+				// later loads start a new batch
+				l.batch = nil
+			}
+			l.mu.Unlock()
+			b.found, b.err = l.fetch(b.ids)
+		})
+		if b.err != nil {
+			return nil, resolverError(l.ctx, b.err)
+		}
+		if a, ok := b.found[id]; ok {
+			return a, nil
+		}
+		return nil, nil
+	}
+}
+
+// fetch reads the articles not seen yet in this request with one query, or
+// from the corpus when it was already read
+func (l *articleLoader) fetch(ids []string) (map[string]models.Article, error) {
+	l.mu.Lock()
+	missing := []string{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if _, ok := l.byID[id]; !ok && !seen[id] {
+			missing = append(missing, id)
+			seen[id] = true
+		}
+	}
+	corpus := l.corpus
+	l.mu.Unlock()
+
+	var found map[string]models.Article
+	if len(missing) > 0 && corpus != nil {
+		found = map[string]models.Article{}
+		for _, a := range corpus {
+			if seen[a.ID] {
+				found[a.ID] = a
+			}
+		}
+	} else if len(missing) > 0 {
+		var err error
+		if found, err = fetchArticlesByIDs(l.ctx, missing); err != nil {
+			return nil, err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id, a := range found {
+		l.byID[id] = a
+	}
+	out := make(map[string]models.Article, len(ids))
+	for _, id := range ids {
+		if a, ok := l.byID[id]; ok {
+			out[id] = a
+		}
+	}
+	return out, nil
+}
+
+// takeRelated counts a related lookup against maxRelatedPerRequest
+func (l *articleLoader) takeRelated() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.related >= maxRelatedPerRequest {
+		return &graphQLError{
+			message: "at most " + strconv.Itoa(maxRelatedPerRequest) + " related lookups are allowed per request",
+			code:    problem.CodeValidation,
+			field:   "related",
+		}
+	}
+	l.related++
+	return nil
+}
+
+// sourceNode is a source with the aggregates of its articles
+type sourceNode struct {
+	Name         string
+	ArticleCount int
+	Languages    []string
+	Categories   []string
+	// LatestPublication is the raw date of the newest article
+	LatestPublication string `graphql:"latestPublicationDate"`
+	latestAt          time.Time
+}
+
+// categoryNode is a category label, lowercased, with its article count
+type categoryNode struct {
+	Name         string
+	ArticleCount int
+}
+
+// catalog groups the corpus by source and category once per request
+func (l *articleLoader) catalog() (map[string]*sourceNode, map[string]*categoryNode, error) {
+	corpus, err := l.Corpus()
+	if err != nil {
+		return nil, nil, err
+	}
+	l.catalogOnce.Do(func() {
+		l.sources = map[string]*sourceNode{}
+		l.categories = map[string]*categoryNode{}
+		langs := map[string]map[string]bool{}
+		cats := map[string]map[string]bool{}
+		for _, a := range corpus {
+			key := strings.ToLower(a.SourceName)
+			s, ok := l.sources[key]
+			if !ok {
+				s = &sourceNode{Name: a.SourceName}
+				l.sources[key] = s
+				langs[key], cats[key] = map[string]bool{}, map[string]bool{}
+			}
+			s.ArticleCount++
+			if s.LatestPublication == "" || a.Publication.After(s.latestAt) {
+				s.latestAt, s.LatestPublication = a.Publication, a.PublicationRaw
+			}
+			langs[key][articleLang(a)] = true
+			for _, name := range articleCategories(a) {
+				cats[key][name] = true
+				c, ok := l.categories[name]
+				if !ok {
+					c = &categoryNode{Name: name}
+					l.categories[name] = c
+				}
+				c.ArticleCount++
+			}
+		}
+		for key, s := range l.sources {
+			s.Languages, s.Categories = sortedKeys(langs[key]), sortedKeys(cats[key])
+		}
+	})
+	return l.sources, l.categories, nil
+}
+
+// articleCategories are the lowercased labels inCategory matches an article
+// on: its source labels and a confident auto category
+func articleCategories(a models.Article) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, c := range a.Category {
+		if name := strings.ToLower(strings.TrimSpace(c)); name != "" && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	if a.AutoCategory != nil && a.AutoCategory.Confidence >= autoCategoryMinConfidence && !seen[a.AutoCategory.Category] {
+		out = append(out, a.AutoCategory.Category)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// cursors are opaque offsets into a result list
+const cursorPrefix = "cursor:"
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset after the cursor, or 0 for no cursor
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return 0, invalidArg("after", "is not a cursor from this API")
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix))
+	// offsets are list indexes, so anything past MaxInt32 was not issued here
+	if err != nil || n < 0 || n >= math.MaxInt32 {
+		return 0, invalidArg("after", "is not a cursor from this API")
+	}
+	return n + 1, nil
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	raw := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name    string
+		cursor  string
+		want    int
+		wantErr bool
+	}{
+		{"no cursor", "", 0, false},
+		{"first item", encodeCursor(0), 1, false},
+		{"later item", encodeCursor(41), 42, false},
+		{"largest offset", encodeCursor(math.MaxInt32 - 1), math.MaxInt32, false},
+		{"not base64", "not a cursor!", 0, true},
+		{"wrong prefix", raw("offset:3"), 0, true},
+		{"not a number", raw("cursor:x"), 0, true},
+		{"negative", raw("cursor:-1"), 0, true},
+		{"past MaxInt32", raw("cursor:" + strconv.Itoa(math.MaxInt32)), 0, true},
+		{"overflows int", raw("cursor:99999999999999999999"), 0, true},
+	}
+	for _, tt := range tests {
+		got, err := decodeCursor(tt.cursor)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: decodeCursor = %d, %v; want %d, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
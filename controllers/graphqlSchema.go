@@ -0,0 +1,803 @@
+package controllers
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"news-backend/config"
+	"news-backend/models"
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/graphql-go/graphql"
+)
+
+// graphQLSchema is built once; an invalid schema is a programming error
+var graphQLSchema = mustGraphQLSchema()
+
+// limits shared with the REST endpoints
+const (
+	defaultFirst  = 10
+	maxFirst      = 100
+	maxQueryLen   = 500
+	maxRadiusKM   = 20038
+	defaultNearby = 10
+)
+
+type pageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+// articleEdge carries the score or distance the list was ranked by
+type articleEdge struct {
+	Cursor     string
+	Node       models.Article
+	Score      *float64
+	DistanceKM *float64
+}
+
+type articleConnection struct {
+	Edges      []articleEdge
+	PageInfo   pageInfo
+	TotalCount int
+}
+
+type trendingItem struct {
+	Article    models.Article
+	Score      float64
+	DistanceKM float64
+}
+
+type trendingEdge struct {
+	Cursor string
+	Node   trendingItem
+}
+
+type trendingConnection struct {
+	Edges      []trendingEdge
+	PageInfo   pageInfo
+	TotalCount int
+}
+
+// window is the part of a list of total items a page covers
+func window(total, start, first int) (lo, hi int, info pageInfo) {
+	lo = max(0, min(start, total))
+	hi = min(lo+first, total)
+	info = pageInfo{HasNextPage: hi < total, HasPreviousPage: lo > 0}
+	if hi > lo {
+		s, e := encodeCursor(lo), encodeCursor(hi-1)
+		info.StartCursor, info.EndCursor = &s, &e
+	}
+	return lo, hi, info
+}
+
+// articlePage pages a ranked list; scores and distances are optional
+func articlePage(articles []models.Article, scores, distances []float64, start, first int) articleConnection {
+	lo, hi, info := window(len(articles), start, first)
+	conn := articleConnection{Edges: []articleEdge{}, PageInfo: info, TotalCount: len(articles)}
+	for i := lo; i < hi; i++ {
+		e := articleEdge{Cursor: encodeCursor(i), Node: articles[i]}
+		if scores != nil {
+			e.Score = &scores[i]
+		}
+		if distances != nil {
+			e.DistanceKM = &distances[i]
+		}
+		conn.Edges = append(conn.Edges, e)
+	}
+	return conn
+}
+
+// arguments
+
+func pageArgs(p graphql.ResolveParams) (start, first int, err error) {
+	first, _ = p.Args["first"].(int)
+	if first < 1 || first > maxFirst {
+		return 0, 0, invalidArg("first", "must be between 1 and 100")
+	}
+	after, _ := p.Args["after"].(string)
+	start, err = decodeCursor(after)
+	return start, first, err
+}
+
+// langArg validates the optional lang argument like langParam
+func langArg(p graphql.ResolveParams) (string, error) {
+	raw, _ := p.Args["lang"].(string)
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	lang := services.NormalizeLang(raw)
+	if lang == "" {
+		return "", invalidArg("lang", "must be a language code such as en or hi")
+	}
+	return lang, nil
+}
+
+func textArg(p graphql.ResolveParams, name string) (string, error) {
+	raw, _ := p.Args[name].(string)
+	text := strings.TrimSpace(raw)
+	switch {
+	case text == "":
+		return "", invalidArg(name, "is required")
+	case len(raw) > maxQueryLen:
+		return "", invalidArg(name, "must have at most 500 characters")
+	}
+	return text, nil
+}
+
+// pointArgs validates lat, lon and radiusKm; a missing radius is def
+func pointArgs(p graphql.ResolveParams, def float64) (lat, lon, radius float64, err error) {
+	lat, _ = p.Args["lat"].(float64)
+	lon, _ = p.Args["lon"].(float64)
+	radius = def
+	if r, ok := p.Args["radiusKm"].(float64); ok {
+		radius = r
+	}
+	switch {
+	case lat < -90 || lat > 90:
+		err = invalidArg("lat", "must be a latitude between -90 and 90")
+	case lon < -180 || lon > 180:
+		err = invalidArg("lon", "must be a longitude between -180 and 180")
+	case radius <= 0 || radius > maxRadiusKM:
+		err = invalidArg("radiusKm", "must be greater than 0 and at most 20038")
+	}
+	return lat, lon, radius, err
+}
+
+func loaderFrom(p graphql.ResolveParams) *articleLoader {
+	return stateFrom(p.Context).loader
+}
+
+// corpusArg reads the corpus filtered by the lang argument
+func corpusArg(p graphql.ResolveParams) ([]models.Article, error) {
+	lang, err := langArg(p)
+	if err != nil {
+		return nil, err
+	}
+	corpus, err := loaderFrom(p).Corpus()
+	if err != nil {
+		return nil, resolverError(p.Context, err)
+	}
+	return filterLang(corpus, lang), nil
+}
+
+// orderedPage sorts the matches by the orderBy argument and pages them
+func orderedPage(p graphql.ResolveParams, matches []models.Article) (interface{}, error) {
+	start, first, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	if p.Args["orderBy"] == "relevance" {
+		sortByRelevance(matches)
+	} else {
+		sortNewest(matches)
+	}
+	return articlePage(matches, nil, nil, start, first), nil
+}
+
+// scoredPage pages a ranking, exposing each score on its edge
+func scoredPage(p graphql.ResolveParams, ranked []services.ScoredArticle) (interface{}, error) {
+	start, first, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	articles := make([]models.Article, len(ranked))
+	scores := make([]float64, len(ranked))
+	for i, r := range ranked {
+		articles[i], scores[i] = r.Article, r.Score
+	}
+	return articlePage(articles, scores, nil, start, first), nil
+}
+
+func sourceOf(p graphql.ResolveParams, name string) (*sourceNode, error) {
+	sources, _, err := loaderFrom(p).catalog()
+	if err != nil {
+		return nil, resolverError(p.Context, err)
+	}
+	return sources[strings.ToLower(strings.TrimSpace(name))], nil
+}
+
+func categoryOf(p graphql.ResolveParams, name string) (*categoryNode, error) {
+	_, categories, err := loaderFrom(p).catalog()
+	if err != nil {
+		return nil, resolverError(p.Context, err)
+	}
+	return categories[strings.ToLower(strings.TrimSpace(name))], nil
+}
+
+func mustGraphQLSchema() graphql.Schema {
+	pageArgConfig := func(extra graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{
+			"first": {Type: graphql.Int, DefaultValue: defaultFirst, Description: "Page size, 1 to 100."},
+			"after": {Type: graphql.String, Description: "Cursor of the last edge of the previous page."},
+			"lang":  {Type: graphql.String, Description: "Only articles in this language, such as en or hi."},
+		}
+		for k, v := range extra {
+			args[k] = v
+		}
+		return args
+	}
+	nonNullString := graphql.NewNonNull(graphql.String)
+	nonNullFloat := graphql.NewNonNull(graphql.Float)
+	nonNullInt := graphql.NewNonNull(graphql.Int)
+	stringList := graphql.NewNonNull(graphql.NewList(nonNullString))
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     {Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": {Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     {Type: graphql.String},
+			"endCursor":       {Type: graphql.String},
+		},
+	})
+	orderType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "ArticleOrder",
+		Values: graphql.EnumValueConfigMap{
+			"NEWEST":    {Value: "newest", Description: "Publication date, newest first."},
+			"RELEVANCE": {Value: "relevance", Description: "Computed relevance, highest first."},
+		},
+	})
+	searchModeType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SearchMode",
+		Values: graphql.EnumValueConfigMap{
+			"LEXICAL": {Value: "lexical", Description: "Text matches blended with relevance."},
+			"HYBRID":  {Value: "hybrid", Description: "Text matches fused with semantic neighbours."},
+		},
+	})
+	predictionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CategoryPrediction",
+		Fields: graphql.Fields{
+			"category":   {Type: nonNullString},
+			"confidence": {Type: nonNullFloat},
+			"model":      {Type: nonNullString},
+		},
+	})
+	relevanceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "RelevanceBreakdown",
+		Description: "A computed relevance score and its components, each in [0,1].",
+		Fields: graphql.Fields{
+			"score":     {Type: nonNullFloat},
+			"editorial": {Type: nonNullFloat},
+			"sourceReliability": {Type: nonNullFloat, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(services.RelevanceBreakdown).Source, nil
+			}},
+			"freshness":  {Type: nonNullFloat},
+			"engagement": {Type: nonNullFloat},
+			"quality":    {Type: nonNullFloat},
+			"coverage":   {Type: nonNullFloat},
+			"computedAt": {Type: graphql.DateTime},
+		},
+	})
+	queryAnalysisType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "QueryAnalysis",
+		Description: "Entities, categories, sources and intent extracted from a query.",
+		Fields: graphql.Fields{
+			"entities":   {Type: stringList},
+			"categories": {Type: stringList},
+			"sources":    {Type: stringList},
+			"intent":     {Type: nonNullString},
+			"location": {Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Location",
+				Fields: graphql.Fields{
+					"latitude":  {Type: nonNullFloat},
+					"longitude": {Type: nonNullFloat},
+				},
+			})},
+		},
+	})
+
+	var articleType, sourceType, categoryType *graphql.Object
+	articleEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleEdge",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"cursor":     {Type: nonNullString},
+				"node":       {Type: graphql.NewNonNull(articleType)},
+				"score":      {Type: graphql.Float, Description: "Ranking score, for search results."},
+				"distanceKm": {Type: graphql.Float, Description: "Distance from the query point, for nearby results."},
+			}
+		}),
+	})
+	articleConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ArticleConnection",
+		Fields: graphql.Fields{
+			"edges":      {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(articleEdgeType)))},
+			"pageInfo":   {Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": {Type: nonNullInt},
+		},
+	})
+	listArgs := pageArgConfig(graphql.FieldConfigArgument{
+		"orderBy": {Type: orderType, DefaultValue: "newest"},
+	})
+	relatedType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RelatedArticle",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"article":    {Type: graphql.NewNonNull(articleType)},
+				"score":      {Type: nonNullFloat, Description: "Similarity blended with shared categories, entities and publication time."},
+				"similarity": {Type: nonNullFloat, Description: "Raw text similarity."},
+			}
+		}),
+	})
+
+	articleType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Article",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":              {Type: graphql.NewNonNull(graphql.ID)},
+				"title":           {Type: nonNullString},
+				"description":     {Type: nonNullString},
+				"url":             {Type: nonNullString},
+				"publicationDate": {Type: nonNullString, Description: "The date as the source published it."},
+				"publishedAt": {Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if t := p.Source.(models.Article).Publication; !t.IsZero() {
+						return t, nil
+					}
+					return nil, nil
+				}},
+				"lang": {Type: nonNullString, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return articleLang(p.Source.(models.Article)), nil
+				}},
+				"relevanceScore": {Type: nonNullFloat, Description: "Computed relevance used for ranking.", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return services.RelevanceOf(p.Source.(models.Article)), nil
+				}},
+				"editorialScore": {Type: nonNullFloat, Description: "Score from the source feed.", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(models.Article).RelevanceScore, nil
+				}},
+				"relevance": {Type: relevanceType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if b, ok := services.ExplainRelevance(p.Source.(models.Article).ID); ok {
+						return b, nil
+					}
+					return nil, nil
+				}},
+				"summary": {
+					Type:        nonNullString,
+					Description: "Summary, translated into lang (default: the Accept-Language preference) once a translation is cached.",
+					Args:        graphql.FieldConfigArgument{"lang": {Type: graphql.String}},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						a := p.Source.(models.Article)
+						want := stateFrom(p.Context).lang
+						if raw, _ := p.Args["lang"].(string); raw != "" {
+							if want = services.NormalizeLang(raw); want == "" {
+								return nil, invalidArg("lang", "must be a language code such as en or hi")
+							}
+						}
+						if want != "" && want != articleLang(a) {
+							if summary, ok := services.CachedSummary(a.ID, a.Title, a.Description, want); ok {
+								return summary, nil
+							}
+						}
+						summary, _ := services.GenerateSummary(a.Title, a.Description)
+						return summary, nil
+					},
+				},
+				"latitude":  {Type: nonNullFloat},
+				"longitude": {Type: nonNullFloat},
+				"entities": {Type: stringList, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if e := p.Source.(models.Article).Entities; e != nil {
+						return e, nil
+					}
+					return []string{}, nil
+				}},
+				"autoCategory": {Type: predictionType},
+				"source": {Type: graphql.NewNonNull(sourceType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					a := p.Source.(models.Article)
+					s, err := sourceOf(p, a.SourceName)
+					if err != nil || s != nil {
+						return s, err
+					}
+					return &sourceNode{Name: a.SourceName, Languages: []string{}, Categories: []string{}}, nil
+				}},
+				"categories": {
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+					Description: "Source categories and a confident auto category.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						out := []*categoryNode{}
+						for _, name := range articleCategories(p.Source.(models.Article)) {
+							c, err := categoryOf(p, name)
+							if err != nil {
+								return nil, err
+							}
+							if c == nil {
+								c = &categoryNode{Name: name}
+							}
+							out = append(out, c)
+						}
+						return out, nil
+					},
+				},
+				"related": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(relatedType))),
+					Args: graphql.FieldConfigArgument{
+						"first": {Type: graphql.Int, DefaultValue: 5, Description: "At most 100."},
+						"lang":  {Type: graphql.String},
+					},
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						first, _ := p.Args["first"].(int)
+						if first < 1 || first > maxFirst {
+							return nil, invalidArg("first", "must be between 1 and 100")
+						}
+						lang, err := langArg(p)
+						if err != nil {
+							return nil, err
+						}
+						if err := loaderFrom(p).takeRelated(); err != nil {
+							return nil, err
+						}
+						corpus, err := loaderFrom(p).Corpus()
+						if err != nil {
+							return nil, resolverError(p.Context, err)
+						}
+						// rank against the whole corpus so the cached index is reused, then filter
+						n := first
+						if lang != "" {
+							n = len(corpus)
+						}
+						out := []services.RelatedItem{}
+						for _, r := range services.RelatedArticles(p.Source.(models.Article), corpus, n) {
+							if len(out) >= first {
+								break
+							}
+							if lang == "" || articleLang(r.Article) == lang {
+								out = append(out, r)
+							}
+						}
+						return out, nil
+					},
+				},
+			}
+		}),
+	})
+
+	sourceType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Source",
+		Description: "A news source and the aggregates of its articles.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":                  {Type: nonNullString},
+				"articleCount":          {Type: nonNullInt},
+				"languages":             {Type: stringList},
+				"latestPublicationDate": {Type: graphql.String},
+				"categories": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					out := []*categoryNode{}
+					for _, name := range p.Source.(*sourceNode).Categories {
+						c, err := categoryOf(p, name)
+						if err != nil {
+							return nil, err
+						}
+						if c != nil {
+							out = append(out, c)
+						}
+					}
+					return out, nil
+				}},
+				"articles": {Type: graphql.NewNonNull(articleConnectionType), Args: listArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					return orderedPage(p, filterArticles(corpus, fromSource(p.Source.(*sourceNode).Name)))
+				}},
+			}
+		}),
+	})
+
+	categoryType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Category",
+		Description: "A category label, lowercased, as matched by the category filter.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name":         {Type: nonNullString},
+				"articleCount": {Type: nonNullInt},
+				"canonical": {Type: graphql.String, Description: "The canonical topic, when the label carries one.", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if c, ok := services.CanonicalCategory(p.Source.(*categoryNode).Name); ok {
+						return c, nil
+					}
+					return nil, nil
+				}},
+				"articles": {Type: graphql.NewNonNull(articleConnectionType), Args: listArgs, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					return orderedPage(p, filterArticles(corpus, inCategory(p.Source.(*categoryNode).Name)))
+				}},
+			}
+		}),
+	})
+
+	trendingItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TrendingItem",
+		Fields: graphql.Fields{
+			"article":    {Type: graphql.NewNonNull(articleType)},
+			"score":      {Type: nonNullFloat, Description: "Recency and distance weighted engagement."},
+			"distanceKm": {Type: nonNullFloat},
+		},
+	})
+	trendingConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TrendingConnection",
+		Fields: graphql.Fields{
+			"edges": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name: "TrendingEdge",
+				Fields: graphql.Fields{
+					"cursor": {Type: nonNullString},
+					"node":   {Type: graphql.NewNonNull(trendingItemType)},
+				},
+			}))))},
+			"pageInfo":   {Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": {Type: nonNullInt},
+		},
+	})
+
+	pointArgConfig := func(radius interface{}, doc string) graphql.FieldConfigArgument {
+		return pageArgConfig(graphql.FieldConfigArgument{
+			"lat":      {Type: nonNullFloat},
+			"lon":      {Type: nonNullFloat},
+			"radiusKm": {Type: graphql.Float, DefaultValue: radius, Description: doc},
+		})
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"article": {
+				Type:        articleType,
+				Description: "An article by ID. Lookups in one query are batched.",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					return loaderFrom(p).Load(id), nil
+				},
+			},
+			"articlesById": {
+				Type:        graphql.NewNonNull(graphql.NewList(articleType)),
+				Description: "Articles by ID in the order given, null where there is none.",
+				Args:        graphql.FieldConfigArgument{"ids": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ids, _ := p.Args["ids"].([]interface{})
+					if len(ids) > maxFirst {
+						return nil, invalidArg("ids", "must have at most 100 items")
+					}
+					out := make([]interface{}, len(ids))
+					for i, id := range ids {
+						out[i] = loaderFrom(p).Load(id.(string))
+					}
+					return out, nil
+				},
+			},
+			"articles": {
+				Type:        graphql.NewNonNull(articleConnectionType),
+				Description: "Articles matching every filter given, like the category, source and score endpoints.",
+				Args: pageArgConfig(graphql.FieldConfigArgument{
+					"category": {Type: graphql.String},
+					"source":   {Type: graphql.String},
+					"minScore": {Type: graphql.Float, Description: "Minimum computed relevance, 0 to 1."},
+					"orderBy":  {Type: orderType, DefaultValue: "newest"},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					preds := []func(models.Article) bool{}
+					if c, _ := p.Args["category"].(string); c != "" {
+						preds = append(preds, inCategory(c))
+					}
+					if s, _ := p.Args["source"].(string); s != "" {
+						preds = append(preds, fromSource(s))
+					}
+					if t, ok := p.Args["minScore"].(float64); ok {
+						if t < 0 || t > 1 {
+							return nil, invalidArg("minScore", "must be between 0 and 1")
+						}
+						preds = append(preds, minRelevance(t))
+					}
+					return orderedPage(p, filterArticles(corpus, preds...))
+				},
+			},
+			"search": {
+				Type:        graphql.NewNonNull(articleConnectionType),
+				Description: "Full-text search, like /api/v1/news/search.",
+				Args: pageArgConfig(graphql.FieldConfigArgument{
+					"query": {Type: nonNullString},
+					"mode":  {Type: searchModeType, DefaultValue: "lexical"},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					q, err := textArg(p, "query")
+					if err != nil {
+						return nil, err
+					}
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					mode, _ := p.Args["mode"].(string)
+					ranked, err := searchRank(p.Context, q, mode, corpus)
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					return scoredPage(p, ranked)
+				},
+			},
+			"semanticSearch": {
+				Type:        graphql.NewNonNull(articleConnectionType),
+				Description: "Search by meaning using embeddings.",
+				Args:        pageArgConfig(graphql.FieldConfigArgument{"query": {Type: nonNullString}}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					q, err := textArg(p, "query")
+					if err != nil {
+						return nil, err
+					}
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					ranked, err := semanticRank(p.Context, q, corpus)
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					return scoredPage(p, ranked)
+				},
+			},
+			"nearby": {
+				Type:        graphql.NewNonNull(articleConnectionType),
+				Description: "Articles within radiusKm of a point, nearest first.",
+				Args:        pointArgConfig(defaultNearby, "At most 20038."),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					lat, lon, radius, err := pointArgs(p, defaultNearby)
+					if err != nil {
+						return nil, err
+					}
+					start, first, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					corpus, err := corpusArg(p)
+					if err != nil {
+						return nil, err
+					}
+					list := articlesNear(p.Context, corpus, lat, lon, radius)
+					articles := make([]models.Article, len(list))
+					distances := make([]float64, len(list))
+					for i, n := range list {
+						articles[i], distances[i] = n.Article, n.DistanceKM
+					}
+					return articlePage(articles, nil, distances, start, first), nil
+				},
+			},
+			"trending": {
+				Type:        graphql.NewNonNull(trendingConnectionType),
+				Description: "Trending articles around a point.",
+				Args:        pointArgConfig(nil, "Defaults to trending.default_radius_km."),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					lat, lon, radius, err := pointArgs(p, config.Get().Trending.DefaultRadius)
+					if err != nil {
+						return nil, err
+					}
+					start, first, err := pageArgs(p)
+					if err != nil {
+						return nil, err
+					}
+					lang, err := langArg(p)
+					if err != nil {
+						return nil, err
+					}
+					// the cache holds the full ranking, so paging needs no extra work
+					top, err := services.GetTrendingForLocation(p.Context, lat, lon, radius, math.MaxInt32)
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					items := []trendingItem{}
+					for _, t := range top {
+						if lang == "" || articleLang(t.Article) == lang {
+							d := haversine(lat, lon, t.Article.Latitude, t.Article.Longitude)
+							items = append(items, trendingItem{Article: t.Article, Score: t.Score, DistanceKM: d})
+						}
+					}
+					lo, hi, info := window(len(items), start, first)
+					conn := trendingConnection{Edges: []trendingEdge{}, PageInfo: info, TotalCount: len(items)}
+					for i := lo; i < hi; i++ {
+						conn.Edges = append(conn.Edges, trendingEdge{Cursor: encodeCursor(i), Node: items[i]})
+					}
+					return conn, nil
+				},
+			},
+			"source": {
+				Type: sourceType,
+				Args: graphql.FieldConfigArgument{"name": {Type: nonNullString, Description: "Matched case-insensitively."}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					if s, err := sourceOf(p, name); err != nil || s != nil {
+						return s, err
+					}
+					return nil, nil
+				},
+			},
+			"sources": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(sourceType))),
+				Description: "Every source, most articles first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					sources, _, err := loaderFrom(p).catalog()
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					out := make([]*sourceNode, 0, len(sources))
+					for _, s := range sources {
+						out = append(out, s)
+					}
+					sortByCount(out, func(s *sourceNode) (int, string) { return s.ArticleCount, s.Name })
+					return out, nil
+				},
+			},
+			"category": {
+				Type: categoryType,
+				Args: graphql.FieldConfigArgument{"name": {Type: nonNullString, Description: "Matched case-insensitively."}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name, _ := p.Args["name"].(string)
+					if c, err := categoryOf(p, name); err != nil || c != nil {
+						return c, err
+					}
+					return nil, nil
+				},
+			},
+			"categories": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+				Description: "Every category, most articles first.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					_, categories, err := loaderFrom(p).catalog()
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					out := make([]*categoryNode, 0, len(categories))
+					for _, c := range categories {
+						out = append(out, c)
+					}
+					sortByCount(out, func(c *categoryNode) (int, string) { return c.ArticleCount, c.Name })
+					return out, nil
+				},
+			},
+			"analyzeQuery": {
+				Type:        graphql.NewNonNull(queryAnalysisType),
+				Description: "Extract entities and intent from a query. Requires the editor role.",
+				Args:        graphql.FieldConfigArgument{"query": {Type: nonNullString}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if !services.RoleAllows(stateFrom(p.Context).principal.Role, services.RoleEditor) {
+						return nil, &graphQLError{message: "analyzeQuery requires the editor role", code: problem.CodeForbidden, field: "analyzeQuery"}
+					}
+					q, err := textArg(p, "query")
+					if err != nil {
+						return nil, err
+					}
+					out, err := services.ExtractEntitiesAndIntent(q)
+					if err != nil {
+						return nil, resolverError(p.Context, err)
+					}
+					return out, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic("graphql schema: " + err.Error())
+	}
+	return schema
+}
+
+// sortByCount orders by count descending, then by name
+func sortByCount[T any](list []T, key func(T) (int, string)) {
+	sort.Slice(list, func(i, j int) bool {
+		ci, ni := key(list[i])
+		cj, nj := key(list[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return ni < nj
+	})
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name                     string
+		total, start, first      int
+		lo, hi                   int
+		hasNext, hasPrev, cursor bool
+	}{
+		{"first page", 10, 0, 3, 0, 3, true, false, true},
+		{"middle page", 10, 3, 3, 3, 6, true, true, true},
+		{"last page is cut short", 10, 8, 5, 8, 10, false, true, true},
+		{"start past the end", 10, 20, 5, 10, 10, false, true, false},
+		{"largest cursor", 10, math.MaxInt32, 100, 10, 10, false, true, false},
+		{"negative start", 10, -5, 3, 0, 3, true, false, true},
+		{"empty list", 0, 0, 5, 0, 0, false, false, false},
+	}
+	for _, tt := range tests {
+		lo, hi, info := window(tt.total, tt.start, tt.first)
+		if lo != tt.lo || hi != tt.hi {
+			t.Errorf("%s: window = [%d, %d), want [%d, %d)", tt.name, lo, hi, tt.lo, tt.hi)
+		}
+		if info.HasNextPage != tt.hasNext || info.HasPreviousPage != tt.hasPrev {
+			t.Errorf("%s: next=%v prev=%v, want next=%v prev=%v", tt.name, info.HasNextPage, info.HasPreviousPage, tt.hasNext, tt.hasPrev)
+		}
+		if (info.StartCursor != nil) != tt.cursor || (info.EndCursor != nil) != tt.cursor {
+			t.Errorf("%s: cursors set = %v, want %v", tt.name, info.StartCursor != nil, tt.cursor)
+		}
+		// the end cursor resumes right after the page
+		if tt.cursor {
+			if next, err := decodeCursor(*info.EndCursor); err != nil || next != hi {
+				t.Errorf("%s: end cursor resumes at %d (%v), want %d", tt.name, next, err, hi)
+			}
+		}
+	}
+}
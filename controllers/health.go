@@ -1,7 +1,17 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/db"
+	"news-backend/migrations"
+	"news-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,8 +25,8 @@ type HealthCheckResponse struct {
 // HealthCheck handles the health check endpoint
 func HealthCheck(c *gin.Context) {
 	dbStatus := "disconnected"
-	if mongoClient != nil {
-		err := mongoClient.Ping(c, nil)
+	if client := db.Client(); client != nil {
+		err := client.Ping(c.Request.Context(), nil)
 		if err == nil {
 			dbStatus = "connected"
 		}
@@ -35,3 +45,198 @@ func HealthCheck(c *gin.Context) {
 
 	c.JSON(status, response)
 }
+
+var startedAt = time.Now()
+
+// GET /livez
+// the process is up and serving; dependencies are not checked
+func Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "uptime_seconds": int64(time.Since(startedAt).Seconds())})
+}
+
+// check statuses
+const (
+	checkOK       = "ok"
+	checkDegraded = "degraded" // working but impaired; never fails readiness
+	checkFail     = "fail"
+)
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Status    string      `json:"status"`
+	Critical  bool        `json:"critical"` // a failing critical check makes the service not ready
+	LatencyMS float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+type readinessCheck struct {
+	name     string
+	critical bool
+	// needsDB checks only degrade the service while it runs read-only from
+	// the snapshot
+	needsDB bool
+	run     func(ctx context.Context) (status string, details interface{}, err error)
+}
+
+var readinessChecks = []readinessCheck{
+	{"mongo", true, true, checkMongo},
+	{"migrations", true, true, checkMigrations},
+	{"seed", true, true, taskCheck(services.TaskSeed)},
+	{"trending", true, false, checkTrending},
+	{"index", false, true, taskCheck(services.TaskIndex)},
+	{"llm", false, false, checkLLM},
+	{"ingestion", true, true, checkIngestion},
+}
+
+// GET /readyz
+// every dependency check with its latency; 503 while a critical one fails.
+// With the database down but a snapshot loaded the service is "degraded" and
+// still ready, since reads are served read-only.
+func Readyz(c *gin.Context) {
+	timeout := config.Get().Health.CheckTimeout.D()
+	snap, snapInfo := services.ArticleSnapshot()
+	readOnly := !services.DatabaseAvailable()
+	canServeSnapshot := readOnly && len(snap) > 0
+	results := make(map[string]CheckResult, len(readinessChecks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range readinessChecks {
+		wg.Add(1)
+		go func(chk readinessCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+			defer cancel()
+			start := time.Now()
+			status, details, err := chk.run(ctx)
+			r := CheckResult{
+				Status:    status,
+				Critical:  chk.critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+				Details:   details,
+			}
+			if err != nil {
+				r.Error = err.Error()
+			}
+			if chk.needsDB && canServeSnapshot && r.Status == checkFail {
+				r.Status = checkDegraded
+			}
+			mu.Lock()
+			results[chk.name] = r
+			mu.Unlock()
+		}(chk)
+	}
+	wg.Wait()
+
+	code, status := http.StatusOK, "ready"
+	for _, r := range results {
+		switch {
+		case r.Critical && r.Status == checkFail:
+			code, status = http.StatusServiceUnavailable, "not_ready"
+		case r.Status == checkDegraded && status == "ready":
+			status = "degraded"
+		}
+	}
+	mode := "read_write"
+	if readOnly {
+		mode = "read_only"
+	}
+	c.JSON(code, gin.H{"status": status, "mode": mode, "snapshot": snapInfo, "checks": results})
+}
+
+func checkMongo(ctx context.Context) (string, interface{}, error) {
+	client := db.Client()
+	if client == nil {
+		return checkFail, nil, errors.New("not connected")
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		return checkFail, nil, err
+	}
+	return checkOK, nil, nil
+}
+
+// checkMigrations passes once every known migration is applied, whether this
+// process ran them or another replica or newsctl did
+func checkMigrations(ctx context.Context) (string, interface{}, error) {
+	task := services.Task(services.TaskMigrations)
+	if task.Status == services.TaskRunning || task.Status == services.TaskPending {
+		return checkFail, task, errors.New("migrations not finished")
+	}
+	database := db.Database()
+	if database == nil {
+		return checkFail, task, errors.New("database not connected")
+	}
+	list, err := migrations.List(ctx, database)
+	if err != nil {
+		return checkFail, task, err
+	}
+	pending := []int{}
+	for _, m := range list {
+		if m.AppliedAt == nil {
+			pending = append(pending, m.Version)
+		}
+	}
+	details := gin.H{"latest": migrations.Latest(), "pending": pending}
+	if len(pending) > 0 {
+		return checkFail, details, fmt.Errorf("%d migrations pending", len(pending))
+	}
+	return checkOK, details, nil
+}
+
+// taskCheck reports a startup task: done or skipped passes, failed fails, and
+// anything else is still in progress
+func taskCheck(task string) func(context.Context) (string, interface{}, error) {
+	return func(context.Context) (string, interface{}, error) {
+		s := services.Task(task)
+		switch s.Status {
+		case services.TaskDone, services.TaskSkipped:
+			return checkOK, s, nil
+		case services.TaskFailed:
+			return checkFail, s, errors.New(s.Error)
+		}
+		return checkFail, s, fmt.Errorf("%s %s", task, s.Status)
+	}
+}
+
+func checkTrending(ctx context.Context) (string, interface{}, error) {
+	status, state, err := taskCheck(services.TaskTrending)(ctx)
+	return status, gin.H{"task": state, "events": services.EventCount()}, err
+}
+
+// checkLLM is degraded rather than failed when the provider is unreachable:
+// summaries and classification fall back to local behaviour
+func checkLLM(ctx context.Context) (string, interface{}, error) {
+	cfg := config.Get().LLM
+	if cfg.APIURL == "" {
+		return checkOK, gin.H{"configured": false}, nil
+	}
+	if err := services.LLMReachable(ctx); err != nil {
+		return checkDegraded, gin.H{"configured": true}, err
+	}
+	return checkOK, gin.H{"configured": true}, nil
+}
+
+// checkIngestion reports how old the newest stored article is. It only fails
+// when health.max_ingestion_lag is set and exceeded.
+func checkIngestion(ctx context.Context) (string, interface{}, error) {
+	if db.Client() == nil {
+		return checkFail, nil, errors.New("database not connected")
+	}
+	newest, err := services.NewestPublication(ctx)
+	if err != nil {
+		return checkFail, nil, err
+	}
+	maxLag := config.Get().Health.MaxIngestionLag.D()
+	if newest.IsZero() {
+		return checkOK, gin.H{"articles": 0}, nil
+	}
+	lag := time.Since(newest)
+	details := gin.H{"newest_published_at": newest, "lag_seconds": int64(lag.Seconds())}
+	if maxLag > 0 {
+		details["max_lag_seconds"] = int64(maxLag.Seconds())
+		if lag > maxLag {
+			return checkFail, details, fmt.Errorf("newest article is %s old", lag.Round(time.Second))
+		}
+	}
+	return checkOK, details, nil
+}
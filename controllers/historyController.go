@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"news-backend/models"
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type readRequest struct {
+	ArticleID string   `json:"article_id" binding:"required"`
+	Progress  float64  `json:"progress" binding:"gte=0,lte=1"`
+	Lat       *float64 `json:"lat" binding:"omitempty,gte=-90,lte=90"`
+	Lon       *float64 `json:"lon" binding:"omitempty,gte=-180,lte=180"`
+}
+
+type bookmarkRequest struct {
+	ArticleID string `json:"article_id" binding:"required"`
+}
+
+// pageQuery is the paging of the history and bookmark lists
+type pageQuery struct {
+	Limit  int `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+// historyItem pairs a history or bookmark entry with its article
+type historyItem struct {
+	ArticleID string           `json:"article_id"`
+	Progress  *float64         `json:"progress,omitempty"`
+	At        time.Time        `json:"at"`
+	Article   *responseArticle `json:"article,omitempty"`
+}
+
+// POST /api/v1/users/me/history
+// records a read and feeds a view event into trending
+func RecordRead(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req readRequest
+	if !bindJSON(c, &req) || !requireText(c, "article_id", req.ArticleID) {
+		return
+	}
+	found, err := fetchArticlesByIDs(ctx, []string{req.ArticleID})
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	a, ok := found[req.ArticleID]
+	if !ok {
+		notFound(c, "article")
+		return
+	}
+	entry, newRead, err := services.RecordRead(ctx, id, a.ID, req.Progress)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	// a read counts as one view however often its progress is updated
+	if !newRead {
+		c.JSON(http.StatusOK, entry)
+		return
+	}
+	// use the reader's location when given, else the article's
+	lat, lon := a.Latitude, a.Longitude
+	if req.Lat != nil && req.Lon != nil {
+		lat, lon = *req.Lat, *req.Lon
+	}
+	if err := services.RecordEvent(services.Event{ArticleID: a.ID, Type: "view", Lat: lat, Lon: lon}); err != nil {
+		slog.ErrorContext(ctx, "record view event", "error", err)
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// GET /api/v1/users/me/history?limit=20&offset=0
+func ListReadingHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var q pageQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	entries, total, err := services.ListReads(ctx, id, q.Offset, q.Limit)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ArticleID)
+	}
+	articles, err := fetchArticlesByIDs(ctx, ids)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	items := []historyItem{}
+	for _, e := range entries {
+		progress := e.Progress
+		items = append(items, withArticle(historyItem{ArticleID: e.ArticleID, Progress: &progress, At: e.ReadAt}, articles))
+	}
+	c.JSON(http.StatusOK, gin.H{"history": items, "total": total, "offset": q.Offset})
+}
+
+// POST /api/v1/users/me/bookmarks
+func AddBookmark(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req bookmarkRequest
+	if !bindJSON(c, &req) || !requireText(c, "article_id", req.ArticleID) {
+		return
+	}
+	found, err := fetchArticlesByIDs(ctx, []string{req.ArticleID})
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	if _, ok := found[req.ArticleID]; !ok {
+		notFound(c, "article")
+		return
+	}
+	b, err := services.AddBookmark(ctx, id, req.ArticleID)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// GET /api/v1/users/me/bookmarks?limit=20&offset=0
+func ListBookmarks(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var q pageQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	bookmarks, total, err := services.ListBookmarks(ctx, id, q.Offset, q.Limit)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	ids := make([]string, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		ids = append(ids, b.ArticleID)
+	}
+	articles, err := fetchArticlesByIDs(ctx, ids)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	items := []historyItem{}
+	for _, b := range bookmarks {
+		items = append(items, withArticle(historyItem{ArticleID: b.ArticleID, At: b.CreatedAt}, articles))
+	}
+	c.JSON(http.StatusOK, gin.H{"bookmarks": items, "total": total, "offset": q.Offset})
+}
+
+// DELETE /api/v1/users/me/bookmarks/:article_id
+func RemoveBookmark(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	err := services.RemoveBookmark(c.Request.Context(), id, c.Param("article_id"))
+	if err == services.ErrBookmarkNotFound {
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// withArticle attaches the article when it still exists
+func withArticle(item historyItem, articles map[string]models.Article) historyItem {
+	if a, ok := articles[item.ArticleID]; ok {
+		ra := toResponseArticle(a, nil)
+		item.Article = &ra
+	}
+	return item
+}
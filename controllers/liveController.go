@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/models"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = (wsPongWait * 9) / 10
+	wsMaxMessageSize = 4096
+	wsBackfillLimit  = 10
+	// newest stored articles a backfill picks its matches from
+	wsBackfillScan = 500
+	wsMaxSubs      = 20
+	// inbound message budget per connection
+	wsRatePerSecond = 5
+	wsRateBurst     = 20
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     allowedOrigin,
+}
+
+// allowedOrigin admits clients without an Origin (mobile apps send none), the
+// API's own origin and those in live.allowed_origins
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, o := range strings.Split(config.Get().Live.AllowedOrigins, ",") {
+		o = strings.TrimSuffix(strings.TrimSpace(o), "/")
+		if o == "*" || (o != "" && strings.EqualFold(o, origin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartImportWatcher pushes articles imported by other processes to live
+// feed clients until ctx is done
+func StartImportWatcher(ctx context.Context) {
+	go services.WatchImports(ctx, config.Get().Live.ImportPollInterval.D())
+}
+
+// wsMessage is the envelope for every frame in both directions.
+//
+// client -> server: subscribe, unsubscribe, event, ack
+// server -> client: ack, article, error
+type wsMessage struct {
+	Type         string                 `json:"type"`
+	ID           string                 `json:"id,omitempty"`
+	Subscription *services.Subscription `json:"subscription,omitempty"`
+	Event        *wsEvent               `json:"event,omitempty"`
+	Article      *responseArticle       `json:"article,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+type wsEvent struct {
+	ArticleID string  `json:"article_id"`
+	Type      string  `json:"event_type"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+}
+
+// wsConn holds the state of a single live feed connection
+type wsConn struct {
+	conn    *websocket.Conn
+	send    chan wsMessage
+	limiter *rate.Limiter
+	ctx     context.Context // the upgrade request's, for log request IDs
+
+	mu     sync.Mutex
+	subs   map[string]services.Subscription
+	nextID int
+}
+
+// GET /api/v1/news/live (websocket upgrade)
+func LiveFeed(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade already wrote an HTTP error response
+		return
+	}
+	ws := &wsConn{
+		conn:    conn,
+		send:    make(chan wsMessage, 64),
+		limiter: rate.NewLimiter(rate.Limit(wsRatePerSecond), wsRateBurst),
+		ctx:     c.Request.Context(),
+		subs:    map[string]services.Subscription{},
+	}
+	articles, cancel := services.SubscribeArticles(64)
+	done := make(chan struct{})
+	go ws.writePump(articles, done)
+	ws.readPump()
+	close(done)
+	cancel()
+}
+
+// readPump handles client frames until the connection closes
+func (ws *wsConn) readPump() {
+	defer ws.conn.Close()
+	ws.conn.SetReadLimit(wsMaxMessageSize)
+	ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				slog.WarnContext(ws.ctx, "live feed read", "error", err)
+			}
+			return
+		}
+		if !ws.limiter.Allow() {
+			ws.reply(wsMessage{Type: "error", Error: "rate limit exceeded"})
+			continue
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			ws.reply(wsMessage{Type: "error", Error: "invalid message"})
+			continue
+		}
+		ws.handle(msg)
+	}
+}
+
+func (ws *wsConn) handle(msg wsMessage) {
+	switch msg.Type {
+	case "subscribe":
+		sub := services.Subscription{}
+		if msg.Subscription != nil {
+			sub = *msg.Subscription
+		}
+		ws.mu.Lock()
+		if len(ws.subs) >= wsMaxSubs {
+			ws.mu.Unlock()
+			ws.reply(wsMessage{Type: "error", ID: msg.ID, Error: "too many subscriptions"})
+			return
+		}
+		id := msg.ID
+		if id == "" {
+			ws.nextID++
+			id = fmt.Sprintf("sub-%d", ws.nextID)
+		}
+		ws.subs[id] = sub
+		ws.mu.Unlock()
+		ws.reply(wsMessage{Type: "ack", ID: id})
+		ws.backfill(id, sub)
+	case "unsubscribe":
+		ws.mu.Lock()
+		_, ok := ws.subs[msg.ID]
+		delete(ws.subs, msg.ID)
+		ws.mu.Unlock()
+		if !ok {
+			ws.reply(wsMessage{Type: "error", ID: msg.ID, Error: "unknown subscription"})
+			return
+		}
+		ws.reply(wsMessage{Type: "ack", ID: msg.ID})
+	case "event":
+		if msg.Event == nil {
+			ws.reply(wsMessage{Type: "error", ID: msg.ID, Error: "event required"})
+			return
+		}
+		if msg.Event.ArticleID != "" && !ws.articleExists(msg.ID, msg.Event.ArticleID) {
+			return
+		}
+		err := services.RecordEvent(services.Event{
+			ArticleID: msg.Event.ArticleID,
+			Type:      msg.Event.Type,
+			Lat:       msg.Event.Lat,
+			Lon:       msg.Event.Lon,
+			Ts:        time.Now(),
+		})
+		if err != nil {
+			ws.reply(wsMessage{Type: "error", ID: msg.ID, Error: err.Error()})
+			return
+		}
+		ws.reply(wsMessage{Type: "ack", ID: msg.ID})
+	case "ack":
+		// client acknowledged a pushed article; nothing to do
+	default:
+		ws.reply(wsMessage{Type: "error", ID: msg.ID, Error: "unknown message type"})
+	}
+}
+
+// articleExists looks up an event's article like the REST handlers do,
+// replying with an error frame when it is unknown or cannot be looked up
+func (ws *wsConn) articleExists(msgID, articleID string) bool {
+	ctx, cancel := context.WithTimeout(ws.ctx, 5*time.Second)
+	defer cancel()
+	found, err := fetchArticlesByIDs(ctx, []string{articleID})
+	if err != nil {
+		slog.ErrorContext(ctx, "live feed event lookup", "error", err)
+		ws.reply(wsMessage{Type: "error", ID: msgID, Error: "article lookup failed"})
+		return false
+	}
+	if _, ok := found[articleID]; !ok {
+		ws.reply(wsMessage{Type: "error", ID: msgID, Error: "article not found"})
+		return false
+	}
+	return true
+}
+
+// backfill pushes the most recent matching articles for a fresh
+// subscription, looking only at the newest stored ones
+func (ws *wsConn) backfill(id string, sub services.Subscription) {
+	ctx, cancel := context.WithTimeout(ws.ctx, 5*time.Second)
+	defer cancel()
+	var articles []models.Article
+	var err error
+	if services.DatabaseAvailable() {
+		articles, err = services.RecentArticles(ctx, wsBackfillScan)
+	} else {
+		articles, err = snapshotArticles()
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "live feed backfill", "error", err)
+		return
+	}
+	res := []models.Article{}
+	for _, a := range articles {
+		if sub.Matches(a) {
+			res = append(res, a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Publication.After(res[j].Publication)
+	})
+	for i := 0; i < min(wsBackfillLimit, len(res)); i++ {
+		a := toResponseArticle(res[i], nil)
+		ws.reply(wsMessage{Type: "article", ID: id, Article: &a})
+	}
+}
+
+// reply queues a message without blocking the read loop
+func (ws *wsConn) reply(msg wsMessage) {
+	select {
+	case ws.send <- msg:
+	default:
+		slog.WarnContext(ws.ctx, "live feed send buffer full, dropping message", "type", msg.Type)
+	}
+}
+
+// writePump serialises all writes to the socket and forwards published articles
+func (ws *wsConn) writePump(articles <-chan models.Article, done <-chan struct{}) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		ws.conn.Close()
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-ws.send:
+			if err := ws.write(msg); err != nil {
+				return
+			}
+		case a, ok := <-articles:
+			if !ok {
+				return
+			}
+			ws.mu.Lock()
+			matched := ""
+			for id, sub := range ws.subs {
+				if sub.Matches(a) {
+					matched = id
+					break
+				}
+			}
+			ws.mu.Unlock()
+			if matched == "" {
+				continue
+			}
+			ra := toResponseArticle(a, nil)
+			if err := ws.write(wsMessage{Type: "article", ID: matched, Article: &ra}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := ws.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (ws *wsConn) write(msg wsMessage) error {
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return ws.conn.WriteJSON(msg)
+}
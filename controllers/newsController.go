@@ -3,116 +3,312 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"net/http"
-	"os"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"news-backend/config"
+	"news-backend/db"
+	"news-backend/logging"
+	"news-backend/migrations"
 	"news-backend/models"
+	"news-backend/problem"
 	"news-backend/services"
+	"news-backend/tracing"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-var (
-	mongoClient      *mongo.Client
-	mongoOnce        sync.Once
-	databaseName     = "news"
-	collectionName   = "articles"
-	trendingCacheTTL = 60 * time.Second
-)
-
-// ConnectDB connects to MongoDB instance using MONGODB_URI environment variable
-func ConnectDB() {
-	mongoOnce.Do(func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		// Get MongoDB URI from environment variable or use default
-		mongoURI := os.Getenv("MONGODB_URI")
-		if mongoURI == "" {
-			mongoURI = "mongodb://localhost:27017"
+// ConnectDB connects to the MongoDB instance from the mongo config section,
+// retrying with exponential backoff until it answers or ctx is done. While it
+// is unreachable the service runs read-only from an article snapshot loaded
+// from the seed file. Once connected it applies migrations and initialises
+// the services.
+func ConnectDB(ctx context.Context) error {
+	cfg := config.Get().Mongo
+	client, err := db.Connect(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("mongo connect: %w", err)
+	}
+	delay := cfg.RetryInitial.D()
+	for attempt := 1; ; attempt++ {
+		pctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout.D())
+		err = client.Ping(pctx, nil)
+		cancel()
+		if err == nil {
+			break
 		}
+		if attempt == 1 {
+			enterReadOnly(ctx)
+		}
+		slog.WarnContext(ctx, "mongo unreachable, retrying", "attempt", attempt, "retry_in", delay.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > cfg.RetryMax.D() {
+			delay = cfg.RetryMax.D()
+		}
+	}
+	slog.InfoContext(ctx, "mongo connected")
 
-		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	// bring the schema up to date before anything reads or writes articles;
+	// MIGRATE_ON_STARTUP=false leaves it to "newsctl migrate"
+	if cfg.MigrateOnStartup {
+		services.SetTaskState(services.TaskMigrations, services.TaskRunning, nil)
+		mctx, mcancel := context.WithTimeout(ctx, 15*time.Minute)
+		ran, err := migrations.Up(mctx, db.Database(), cfg.ArticlesCollection, 0)
+		mcancel()
 		if err != nil {
-			log.Fatal("mongo connect:", err)
+			services.SetTaskState(services.TaskMigrations, services.TaskFailed, err)
+			return fmt.Errorf("migrate: %w", err)
 		}
-		// ping
-		if err := client.Ping(ctx, nil); err != nil {
-			log.Fatal("mongo ping:", err)
+		if len(ran) > 0 {
+			slog.InfoContext(ctx, "applied migrations", "versions", ran)
 		}
-		mongoClient = client
-		// initialize trending simulation
-		services.InitTrendingSimulator(client, databaseName, collectionName)
-	})
+		services.SetTaskState(services.TaskMigrations, services.TaskDone, nil)
+	} else {
+		services.SetTaskState(services.TaskMigrations, services.TaskSkipped, nil)
+	}
+	services.InitArticles(client, cfg.Database, cfg.ArticlesCollection)
+	services.InitAuth(client, cfg.Database)
+	services.InitUsers(client, cfg.Database)
+	services.InitEmbeddings(client, cfg.Database)
+	services.InitStories(client, cfg.Database)
+	services.InitEntities(client, cfg.Database)
+	// initialize trending simulation
+	services.InitTrendingSimulator(ctx)
+	// only now leave read-only mode, with every service ready for writes
+	services.SetDatabaseAvailable(true)
+	return nil
 }
 
-// SaveArticlesToDB reads JSON file and seeds DB if empty.
-func SaveArticlesToDB() {
-	collection := mongoClient.Database(databaseName).Collection(collectionName)
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-
-	// check count
-	c, err := collection.CountDocuments(ctx, bson.M{})
-	if err != nil {
-		log.Println("count error:", err)
+// enterReadOnly loads the seed file as the article snapshot, unless there
+// already is one, so reads keep working before the database is first reached
+func enterReadOnly(ctx context.Context) {
+	if snap, _ := services.ArticleSnapshot(); len(snap) > 0 {
 		return
 	}
-	if c > 0 {
-		log.Println("articles already present in DB:", c)
+	path := config.Get().Data.SeedFile
+	articles, err := services.LoadNewsDataFromFile(path)
+	if err != nil {
+		slog.ErrorContext(ctx, "load read-only snapshot", "file", path, "error", err)
 		return
 	}
+	for i := range articles {
+		services.PrepareForImport(&articles[i])
+		articles[i].Publication = models.ParsePublication(articles[i].PublicationRaw)
+	}
+	services.SetArticleSnapshot(articles, path)
+	services.InitTrendingFromSnapshot()
+	services.ComputeRelevance(articles)
+	slog.WarnContext(ctx, "serving read-only from snapshot", "file", path, "articles", len(articles))
+}
 
-	articles, err := services.LoadNewsDataFromFile("data/news_data.json")
+// MonitorDB pings MongoDB every mongo.monitor_interval until ctx is done and
+// switches between normal and read-only mode as it goes down and comes back
+func MonitorDB(ctx context.Context) {
+	cfg := config.Get().Mongo
+	t := time.NewTicker(cfg.MonitorInterval.D())
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		client := db.Client()
+		if client == nil {
+			// disconnected during shutdown
+			return
+		}
+		pctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout.D())
+		err := client.Ping(pctx, nil)
+		cancel()
+		up := err == nil
+		if up == services.DatabaseAvailable() {
+			continue
+		}
+		services.SetDatabaseAvailable(up)
+		if up {
+			slog.InfoContext(ctx, "mongo reachable again, leaving read-only mode")
+		} else {
+			_, info := services.ArticleSnapshot()
+			slog.ErrorContext(ctx, "mongo unreachable, serving read-only from snapshot", "error", err, "snapshot_articles", info.Articles)
+		}
+	}
+}
+
+// SaveArticlesToDB seeds the articles collection from data.seed_file.
+// Seeding streams the file, upserts in batches of data.seed_batch_size and
+// resumes where an interrupted run stopped; a file that was already seeded is
+// skipped. Seeding and the indexing it starts stop when ctx is done.
+func SaveArticlesToDB(ctx context.Context) {
+	path, batch := config.Get().Data.SeedFile, config.Get().Data.SeedBatchSize
+	services.SetTaskState(services.TaskSeed, services.TaskRunning, nil)
+	report, err := services.SeedArticles(ctx, path, batch)
 	if err != nil {
-		log.Println("load data:", err)
-		return
+		services.SetTaskState(services.TaskSeed, services.TaskFailed, err)
+	} else {
+		services.SetTaskState(services.TaskSeed, services.TaskDone, nil)
+	}
+	switch {
+	case err != nil:
+		slog.ErrorContext(ctx, "seed articles", "error", err)
+	case report.UpToDate:
+		slog.InfoContext(ctx, "seed file already loaded", "file", path)
+	default:
+		slog.InfoContext(ctx, "seeded articles", "file", path, "read", report.Read, "resumed", report.Resumed,
+			"inserted", report.Inserted, "updated", report.Updated, "skipped", report.Skipped, "skip_reasons", report.Reasons)
+	}
+	// index whatever is stored, including anything a failed run left behind
+	services.SetTaskState(services.TaskIndex, services.TaskRunning, nil)
+	logging.Go(ctx, "index_articles", func(ctx context.Context) error {
+		// articles stored from here on are indexed as they come, after this run
+		startIndexer(ctx)
+		err := indexArticles(ctx, nil)
+		if err != nil {
+			services.SetTaskState(services.TaskIndex, services.TaskFailed, err)
+		} else {
+			services.SetTaskState(services.TaskIndex, services.TaskDone, nil)
+		}
+		return err
+	})
+	if report.Inserted > 0 {
+		// also refresh trending simulator with new articles
+		services.InitTrendingSimulator(ctx)
 	}
-	// prepare insert docs
-	docs := make([]interface{}, 0, len(articles))
-	for _, a := range articles {
-		// keep raw publication string, parse separately in code
-		docs = append(docs, a)
+}
+
+// indexArticles detects languages, tags entities, classifies uncategorised articles and computes embeddings and story clusters for articles in the
+// background, so the first semantic search or story listing is not slow.
+// A nil slice indexes everything in the collection. Steps that fail are
+// logged and skipped; the error returned is the one that stopped the run.
+func indexArticles(ctx context.Context, articles []models.Article) error {
+	indexRunMu.Lock()
+	defer indexRunMu.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	if articles == nil {
+		all, err := fetchAllArticles(ctx)
+		if err != nil {
+			return fmt.Errorf("fetch articles: %w", err)
+		}
+		articles = all
+	}
+	if err := services.DetectStoredLanguages(ctx, articles); err != nil {
+		slog.ErrorContext(ctx, "detect article languages", "error", err)
+	}
+	if err := services.TagStoredArticles(ctx, articles); err != nil {
+		slog.ErrorContext(ctx, "tag article entities", "error", err)
+	}
+	services.EnsureClassifier(articles)
+	if err := services.ClassifyStoredArticles(ctx, articles); err != nil {
+		slog.ErrorContext(ctx, "classify articles", "error", err)
 	}
-	_, err = collection.InsertMany(ctx, docs)
+	if _, err := services.EnsureEmbeddings(ctx, articles); err != nil {
+		return fmt.Errorf("embed articles: %w", err)
+	}
+	if err := services.ClusterArticles(ctx, articles); err != nil {
+		slog.ErrorContext(ctx, "cluster articles", "error", err)
+	}
+	// cluster sizes feed into relevance, so rescore once stories are known
+	all, err := fetchAllArticles(ctx)
 	if err != nil {
-		log.Println("insert many:", err)
-		return
+		return fmt.Errorf("fetch articles for relevance: %w", err)
 	}
-	log.Println("seeded", len(docs), "articles")
-	// also refresh trending simulator with new articles
-	services.InitTrendingSimulator(mongoClient, databaseName, collectionName)
+	services.ComputeRelevance(all)
+	return nil
+}
+
+// indexRunMu keeps the startup run and the indexer from doing the same work twice
+var indexRunMu sync.Mutex
+
+// startIndexer indexes articles inserted or rewritten after seeding, as
+// services.QueueIndex hands them over, until ctx is done
+func startIndexer(ctx context.Context) {
+	services.StartIndexer(ctx, func(ctx context.Context, ids []string) error {
+		articles, err := services.FindArticlesByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		return indexArticles(ctx, articles)
+	})
+}
+
+// StartRelevanceScorer keeps computed relevance scores fresh until ctx is done
+func StartRelevanceScorer(ctx context.Context) {
+	services.StartRelevanceScorer(ctx, config.Get().Cache.RelevanceRefresh.D(), fetchAllArticles)
 }
 
-// helper: fetch all articles
-func fetchAllArticles(ctx context.Context) ([]models.Article, error) {
-	coll := mongoClient.Database(databaseName).Collection(collectionName)
-	cur, err := coll.Find(ctx, bson.M{})
+// helper: fetch all articles. Each successful read refreshes the snapshot,
+// which is served instead while the database is unavailable.
+func fetchAllArticles(ctx context.Context) (_ []models.Article, err error) {
+	ctx, span := tracing.Start(ctx, "articles.fetch_all")
+	defer func() { tracing.End(span, err) }()
+	if !services.DatabaseAvailable() {
+		span.SetAttributes(attribute.Bool("articles.snapshot", true))
+		return snapshotArticles()
+	}
+	out, err := services.LoadAllArticles(ctx)
 	if err != nil {
+		if snap, serr := snapshotArticles(); serr == nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "fetch articles failed, serving snapshot", "error", err)
+			return snap, nil
+		}
 		return nil, err
 	}
-	defer cur.Close(ctx)
-	out := []models.Article{}
-	for cur.Next(ctx) {
-		var a models.Article
-		if err := cur.Decode(&a); err != nil {
-			continue
+	span.SetAttributes(attribute.Int("articles.count", len(out)))
+	services.SetArticleSnapshot(append([]models.Article(nil), out...), "database")
+	return out, nil
+}
+
+// snapshotArticles returns a copy of the article snapshot, since callers may
+// reorder or filter what they get
+func snapshotArticles() ([]models.Article, error) {
+	snap, _ := services.ArticleSnapshot()
+	if len(snap) == 0 {
+		return nil, services.ErrDatabaseUnavailable
+	}
+	return append([]models.Article(nil), snap...), nil
+}
+
+// helper: fetch articles by id, keyed by id
+func fetchArticlesByIDs(ctx context.Context, ids []string) (_ map[string]models.Article, err error) {
+	ctx, span := tracing.Start(ctx, "articles.fetch_by_ids", attribute.Int("articles.requested", len(ids)))
+	defer func() { tracing.End(span, err) }()
+	out := map[string]models.Article{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	if !services.DatabaseAvailable() {
+		snap, err := snapshotArticles()
+		if err != nil {
+			return nil, err
 		}
-		// parse publication date if available
-		if t, err := time.Parse(time.RFC3339, a.PublicationRaw); err == nil {
-			a.Publication = t
+		want := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			want[id] = true
 		}
-		out = append(out, a)
+		for _, a := range snap {
+			if want[a.ID] {
+				out[a.ID] = a
+			}
+		}
+		return out, nil
+	}
+	found, err := services.FindArticlesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range found {
+		out[a.ID] = a
 	}
 	return out, nil
 }
@@ -132,17 +328,25 @@ func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 
 // response article type
 type responseArticle struct {
-	Title           string   `json:"title"`
-	Description     string   `json:"description"`
-	URL             string   `json:"url"`
-	PublicationDate string   `json:"publication_date"`
-	SourceName      string   `json:"source_name"`
-	Category        []string `json:"category"`
-	RelevanceScore  float64  `json:"relevance_score"`
-	LLMSummary      string   `json:"llm_summary"`
-	Latitude        float64  `json:"latitude"`
-	Longitude       float64  `json:"longitude"`
-	DistanceKM      *float64 `json:"distance_km,omitempty"`
+	ID              string                     `json:"id"`
+	Title           string                     `json:"title"`
+	Description     string                     `json:"description"`
+	URL             string                     `json:"url"`
+	PublicationDate string                     `json:"publication_date"`
+	SourceName      string                     `json:"source_name"`
+	Category        []string                   `json:"category"`
+	RelevanceScore  float64                    `json:"relevance_score"`
+	EditorialScore  float64                    `json:"editorial_score"`
+	LLMSummary      string                     `json:"llm_summary"`
+	SummaryLang     string                     `json:"summary_lang,omitempty"`
+	Lang            string                     `json:"lang"`
+	Latitude        float64                    `json:"latitude"`
+	Longitude       float64                    `json:"longitude"`
+	DistanceKM      *float64                   `json:"distance_km,omitempty"`
+	Entities        []string                   `json:"entities,omitempty"`
+	AutoCategory    *models.CategoryPrediction `json:"auto_category,omitempty"`
+	// Relevance breaks down RelevanceScore when the request asks to explain it
+	Relevance *services.RelevanceBreakdown `json:"relevance,omitempty"`
 }
 
 // helper to build response with LLM summary
@@ -150,270 +354,531 @@ func toResponseArticle(a models.Article, includeDistance *float64) responseArtic
 	// generate summary (heuristic or external depending on env)
 	summary, _ := services.GenerateSummary(a.Title, a.Description)
 	return responseArticle{
+		ID:              a.ID,
 		Title:           a.Title,
 		Description:     a.Description,
 		URL:             a.URL,
 		PublicationDate: a.PublicationRaw,
 		SourceName:      a.SourceName,
 		Category:        a.Category,
-		RelevanceScore:  a.RelevanceScore,
+		RelevanceScore:  services.RelevanceOf(a),
+		EditorialScore:  a.RelevanceScore,
 		LLMSummary:      summary,
+		Lang:            articleLang(a),
 		Latitude:        a.Latitude,
 		Longitude:       a.Longitude,
 		DistanceKM:      includeDistance,
+		Entities:        a.Entities,
+		AutoCategory:    a.AutoCategory,
 	}
 }
 
-// GET /api/v1/news/category?category=Technology&limit=5
+// decorateArticles applies per-request options to a response list: score
+// components when explain=true, and summaries in the Accept-Language language
+// once they are translated (services.CachedSummary)
+func decorateArticles(c *gin.Context, resp []responseArticle) {
+	explain := c.Query("explain") == "true"
+	want := services.PreferredLanguage(c.GetHeader("Accept-Language"))
+	for i := range resp {
+		if explain {
+			if b, ok := services.ExplainRelevance(resp[i].ID); ok {
+				resp[i].Relevance = &b
+			}
+		}
+		if want != "" && want != resp[i].Lang {
+			if summary, ok := services.CachedSummary(resp[i].ID, resp[i].Title, resp[i].Description, want); ok {
+				resp[i].LLMSummary = summary
+				resp[i].SummaryLang = want
+			}
+		}
+	}
+}
+
+// langParam reads the optional ?lang= filter; it writes a 400 and returns
+// false when the value is not a language code
+func langParam(c *gin.Context) (string, bool) {
+	raw := strings.TrimSpace(c.Query("lang"))
+	if raw == "" {
+		return "", true
+	}
+	lang := services.NormalizeLang(raw)
+	if lang == "" {
+		problem.Invalid(c, problem.FieldError{Field: "lang", Code: "lang", Message: "must be a language code such as en or hi"})
+		return "", false
+	}
+	return lang, true
+}
+
+// filterLang keeps articles in the given language; an empty lang keeps all
+func filterLang(articles []models.Article, lang string) []models.Article {
+	if lang == "" {
+		return articles
+	}
+	out := []models.Article{}
+	for _, a := range articles {
+		if articleLang(a) == lang {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// articleLang falls back to detection for articles stored before languages were tagged
+func articleLang(a models.Article) string {
+	return services.ArticleLang(a)
+}
+
+type categoryQuery struct {
+	Category string `form:"category" binding:"required"`
+	Limit    int    `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/category?category=Technology&limit=5&lang=hi
 func GetArticlesByCategory(c *gin.Context) {
 	ctl := c.Request.Context()
-	category := c.Query("category")
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
+	var q categoryQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	category, limit := q.Category, q.Limit
 
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
 	articles, err := fetchAllArticles(ctl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	// filter by category membership (case-insensitive)
-	res := []models.Article{}
+	res := filterArticles(filterLang(articles, lang), inCategory(category))
+	sortNewest(res)
+	// prepare response top N
+	resp := []responseArticle{}
+	for i := 0; i < min(limit, len(res)); i++ {
+		resp = append(resp, toResponseArticle(res[i], nil))
+	}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(res)})
+}
+
+// auto categories below this confidence are not used for category filtering
+const autoCategoryMinConfidence = 0.5
+
+// inCategory matches source categories case-insensitively, and confident auto
+// categories by their canonical name
+func inCategory(category string) func(models.Article) bool {
 	catLower := strings.ToLower(category)
-	for _, a := range articles {
+	canonical, _ := services.CanonicalCategory(category)
+	return func(a models.Article) bool {
 		for _, cat := range a.Category {
 			if strings.ToLower(cat) == catLower {
-				res = append(res, a)
-				break
+				return true
 			}
 		}
+		if a.AutoCategory != nil && a.AutoCategory.Confidence >= autoCategoryMinConfidence {
+			return a.AutoCategory.Category == catLower || a.AutoCategory.Category == canonical
+		}
+		return false
 	}
-	// sort by publication date desc
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Publication.After(res[j].Publication)
-	})
-	// prepare response top N
-	resp := []responseArticle{}
-	for i := 0; i < min(limit, len(res)); i++ {
-		resp = append(resp, toResponseArticle(res[i], nil))
+}
+
+// fromSource matches the source name case-insensitively
+func fromSource(source string) func(models.Article) bool {
+	return func(a models.Article) bool {
+		return strings.EqualFold(a.SourceName, source)
 	}
-	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(res)})
 }
 
-// GET /api/v1/news/score?threshold=0.7&limit=5
+// minRelevance matches articles scoring at least threshold
+func minRelevance(threshold float64) func(models.Article) bool {
+	return func(a models.Article) bool {
+		return services.RelevanceOf(a) >= threshold
+	}
+}
+
+// filterArticles keeps the articles matching every predicate
+func filterArticles(articles []models.Article, preds ...func(models.Article) bool) []models.Article {
+	out := []models.Article{}
+next:
+	for _, a := range articles {
+		for _, p := range preds {
+			if !p(a) {
+				continue next
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// sortNewest orders articles by publication date, newest first
+func sortNewest(articles []models.Article) {
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Publication.After(articles[j].Publication)
+	})
+}
+
+// sortByRelevance orders articles by computed relevance, highest first
+func sortByRelevance(articles []models.Article) {
+	sort.SliceStable(articles, func(i, j int) bool {
+		return services.RelevanceOf(articles[i]) > services.RelevanceOf(articles[j])
+	})
+}
+
+type scoreQuery struct {
+	Threshold float64 `form:"threshold,default=0.5" binding:"gte=0,lte=1"`
+	Limit     int     `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/score?threshold=0.5&limit=5&lang=hi
 func GetArticlesByScore(c *gin.Context) {
 	ctl := c.Request.Context()
-	threshold := parseFloatDefault(c.DefaultQuery("threshold", "0.7"), 0.7)
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
+	var q scoreQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	threshold, limit := q.Threshold, q.Limit
 
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
 	articles, err := fetchAllArticles(ctl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	res := []models.Article{}
-	for _, a := range articles {
-		if a.RelevanceScore >= threshold {
-			res = append(res, a)
-		}
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].RelevanceScore > res[j].RelevanceScore
-	})
+	res := filterArticles(filterLang(articles, lang), minRelevance(threshold))
+	sortByRelevance(res)
 	resp := []responseArticle{}
 	for i := 0; i < min(limit, len(res)); i++ {
 		resp = append(resp, toResponseArticle(res[i], nil))
 	}
+	decorateArticles(c, resp)
 	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(res)})
 }
 
-// GET /api/v1/news/search?query=Elon+Musk&limit=5
+type searchQuery struct {
+	Query string `form:"query" binding:"required,max=500"`
+	Limit int    `form:"limit,default=5" binding:"min=1,max=100"`
+	Mode  string `form:"mode,default=lexical" binding:"oneof=lexical hybrid"`
+}
+
+// GET /api/v1/news/search?query=Elon+Musk&limit=5&mode=lexical|hybrid&lang=hi
 func SearchArticles(c *gin.Context) {
 	ctl := c.Request.Context()
-	q := strings.TrimSpace(c.Query("query"))
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
-	if q == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query required"})
+	var req searchQuery
+	if !bindQuery(c, &req) || !requireText(c, "query", req.Query) {
+		return
+	}
+	q, limit, mode := strings.TrimSpace(req.Query), req.Limit, req.Mode
+	lang, ok := langParam(c)
+	if !ok {
 		return
 	}
 	articles, err := fetchAllArticles(ctl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	qLower := strings.ToLower(q)
-	type scored struct {
-		A     models.Article
-		Score float64
+	candidates, err := searchRank(ctl, q, mode, filterLang(articles, lang))
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	resp := []responseArticle{}
+	for i := 0; i < min(limit, len(candidates)); i++ {
+		resp = append(resp, toResponseArticle(candidates[i].Article, nil))
 	}
-	candidates := []scored{}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(candidates)})
+}
+
+type semanticSearchQuery struct {
+	Query string `form:"query" binding:"required,max=500"`
+	Limit int    `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/semantic-search?query=cricket+match+result&limit=5&lang=hi
+func SemanticSearch(c *gin.Context) {
+	ctl := c.Request.Context()
+	var req semanticSearchQuery
+	if !bindQuery(c, &req) || !requireText(c, "query", req.Query) {
+		return
+	}
+	q, limit := strings.TrimSpace(req.Query), req.Limit
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
+	articles, err := fetchAllArticles(ctl)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	articles = filterLang(articles, lang)
+	ranked, err := semanticRank(ctl, q, articles)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	resp := []responseArticle{}
+	for i := 0; i < min(limit, len(ranked)); i++ {
+		resp = append(resp, toResponseArticle(ranked[i].Article, nil))
+	}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(ranked)})
+}
+
+// searchRank ranks articles for a full-text query; mode hybrid fuses text
+// matches with vector neighbours by reciprocal rank
+func searchRank(ctx context.Context, q, mode string, articles []models.Article) (_ []services.ScoredArticle, err error) {
+	ctx, span := tracing.Start(ctx, "search.lexical_rank", attribute.String("search.mode", mode), attribute.Int("search.candidates", len(articles)))
+	defer func() { tracing.End(span, err) }()
+	var candidates []services.ScoredArticle
+	if mode == "hybrid" {
+		semantic, err := semanticRank(ctx, q, articles)
+		if err != nil {
+			return nil, err
+		}
+		candidates = services.FuseRankings(lexicalRank(q, articles, true), semantic)
+	} else {
+		candidates = lexicalRank(q, articles, false)
+	}
+	span.SetAttributes(attribute.Int("search.results", len(candidates)))
+	return candidates, nil
+}
+
+// lexicalRank scores articles by query occurrences blended with computed relevance.
+// Query and articles are tokenized by their own language, and an article
+// matches when it contains every query token. With matchesOnly, articles that
+// do not match are dropped.
+func lexicalRank(q string, articles []models.Article, matchesOnly bool) []services.ScoredArticle {
+	qTokens := services.Tokenize(q)
+	qLower := strings.ToLower(q)
+	candidates := []services.ScoredArticle{}
 	maxTextCount := 1.0
 	for _, a := range articles {
-		text := strings.ToLower(a.Title + " " + a.Description)
-		count := float64(strings.Count(text, qLower))
+		count := 0.0
+		if len(qTokens) == 0 {
+			// query made only of stop words: fall back to substring matching
+			count = float64(strings.Count(strings.ToLower(a.Title+" "+a.Description), qLower))
+		} else {
+			count = tokenMatches(qTokens, services.TokenizeLang(a.Title+" "+a.Description, articleLang(a)))
+		}
 		if count > maxTextCount {
 			maxTextCount = count
 		}
-		if count > 0 || a.RelevanceScore > 0 {
-			candidates = append(candidates, scored{A: a, Score: count})
+		if count > 0 || (!matchesOnly && services.RelevanceOf(a) > 0) {
+			candidates = append(candidates, services.ScoredArticle{Article: a, Score: count})
 		}
 	}
-	// combine scores: 60% relevance_score, 40% normalized text match
+	// combine scores: 60% relevance, 40% normalized text match
 	for i := range candidates {
 		textScore := candidates[i].Score / maxTextCount
-		final := 0.6*candidates[i].A.RelevanceScore + 0.4*textScore
+		final := 0.6*services.RelevanceOf(candidates[i].Article) + 0.4*textScore
 		candidates[i].Score = final
 	}
 	sort.Slice(candidates, func(i, j int) bool {
 		return candidates[i].Score > candidates[j].Score
 	})
-	resp := []responseArticle{}
-	for i := 0; i < min(limit, len(candidates)); i++ {
-		resp = append(resp, toResponseArticle(candidates[i].A, nil))
+	return candidates
+}
+
+// tokenMatches counts occurrences of query tokens in text tokens, or 0 unless
+// every query token occurs
+func tokenMatches(query, text []string) float64 {
+	freq := map[string]int{}
+	for _, t := range text {
+		freq[t]++
+	}
+	total := 0
+	for _, t := range query {
+		if freq[t] == 0 {
+			return 0
+		}
+		total += freq[t]
 	}
-	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(candidates)})
+	return float64(total)
 }
 
-// GET /api/v1/news/source?source=Reuters&limit=5
+// semanticRank embeds the query and orders articles by vector similarity
+func semanticRank(ctx context.Context, q string, articles []models.Article) (_ []services.ScoredArticle, err error) {
+	ctx, span := tracing.Start(ctx, "search.semantic_rank", attribute.Int("search.candidates", len(articles)))
+	defer func() { tracing.End(span, err) }()
+	vecs, err := services.EnsureEmbeddings(ctx, articles)
+	if err != nil {
+		return nil, err
+	}
+	qv, err := services.EmbedQuery(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return services.SemanticRank(qv, articles, vecs), nil
+}
+
+type sourceQuery struct {
+	Source string `form:"source" binding:"required"`
+	Limit  int    `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/source?source=Reuters&limit=5&lang=hi
 func GetArticlesBySource(c *gin.Context) {
 	ctl := c.Request.Context()
-	source := c.Query("source")
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
-	if source == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "source required"})
+	var q sourceQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	source, limit := q.Source, q.Limit
+	lang, ok := langParam(c)
+	if !ok {
 		return
 	}
 	articles, err := fetchAllArticles(ctl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	srcLower := strings.ToLower(source)
-	res := []models.Article{}
-	for _, a := range articles {
-		if strings.ToLower(a.SourceName) == srcLower {
-			res = append(res, a)
-		}
-	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Publication.After(res[j].Publication)
-	})
+	res := filterArticles(filterLang(articles, lang), fromSource(source))
+	sortNewest(res)
 	resp := []responseArticle{}
 	for i := 0; i < min(limit, len(res)); i++ {
 		resp = append(resp, toResponseArticle(res[i], nil))
 	}
+	decorateArticles(c, resp)
 	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(res)})
 }
 
-// GET /api/v1/news/nearby?lat=37.4&lon=-122.1&radius=10&limit=5
+// radius is in km, at most half the Earth's circumference
+type nearbyQuery struct {
+	Lat    *float64 `form:"lat" binding:"required,gte=-90,lte=90"`
+	Lon    *float64 `form:"lon" binding:"required,gte=-180,lte=180"`
+	Radius float64  `form:"radius,default=10" binding:"gt=0,lte=20038"`
+	Limit  int      `form:"limit,default=5" binding:"min=1,max=100"`
+}
+
+// GET /api/v1/news/nearby?lat=37.4&lon=-122.1&radius=10&limit=5&lang=hi
 func GetNearbyArticles(c *gin.Context) {
 	ctl := c.Request.Context()
-	lat := parseFloatDefault(c.Query("lat"), 0)
-	lon := parseFloatDefault(c.Query("lon"), 0)
-	radius := parseFloatDefault(c.DefaultQuery("radius", "10"), 10) // km
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
+	var q nearbyQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	lat, lon, radius, limit := *q.Lat, *q.Lon, q.Radius, q.Limit
 
-	if lat == 0 && lon == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lon required"})
+	lang, ok := langParam(c)
+	if !ok {
 		return
 	}
 	articles, err := fetchAllArticles(ctl)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
-	type withDist struct {
-		A    models.Article
-		Dist float64
+	list := articlesNear(ctl, filterLang(articles, lang), lat, lon, radius)
+	resp := []responseArticle{}
+	for i := 0; i < min(limit, len(list)); i++ {
+		dist := list[i].DistanceKM
+		resp = append(resp, toResponseArticle(list[i].Article, &dist))
 	}
-	list := []withDist{}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(list)})
+}
+
+// nearbyArticle is an article with its distance from the query point
+type nearbyArticle struct {
+	Article    models.Article
+	DistanceKM float64
+}
+
+// articlesNear returns the articles within radius km of lat/lon, nearest first
+func articlesNear(ctx context.Context, articles []models.Article, lat, lon, radius float64) []nearbyArticle {
+	_, span := tracing.Start(ctx, "nearby.scan", attribute.Int("nearby.candidates", len(articles)), attribute.Float64("nearby.radius_km", radius))
+	defer span.End()
+	list := []nearbyArticle{}
 	for _, a := range articles {
-		d := haversine(lat, lon, a.Latitude, a.Longitude)
-		if d <= radius {
-			list = append(list, withDist{A: a, Dist: d})
+		if d := haversine(lat, lon, a.Latitude, a.Longitude); d <= radius {
+			list = append(list, nearbyArticle{Article: a, DistanceKM: d})
 		}
 	}
 	sort.Slice(list, func(i, j int) bool {
-		return list[i].Dist < list[j].Dist
+		return list[i].DistanceKM < list[j].DistanceKM
 	})
-	resp := []responseArticle{}
-	for i := 0; i < min(limit, len(list)); i++ {
-		dist := list[i].Dist
-		resp = append(resp, toResponseArticle(list[i].A, &dist))
-	}
-	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(list)})
+	span.SetAttributes(attribute.Int("nearby.results", len(list)))
+	return list
+}
+
+type trendingQuery struct {
+	Lat *float64 `form:"lat" binding:"required,gte=-90,lte=90"`
+	Lon *float64 `form:"lon" binding:"required,gte=-180,lte=180"`
+	// defaults to trending.default_radius_km
+	Radius float64 `form:"radius" binding:"omitempty,gt=0,lte=20038"`
+	Limit  int     `form:"limit,default=5" binding:"min=1,max=100"`
 }
 
-// GET /api/v1/news/trending?lat=37.4&lon=-122.1&limit=5&radius=50
+// GET /api/v1/news/trending?lat=37.4&lon=-122.1&limit=5&radius=50&lang=hi
 func GetTrending(c *gin.Context) {
-	lat := parseFloatDefault(c.Query("lat"), 0)
-	lon := parseFloatDefault(c.Query("lon"), 0)
-	limit := parseLimit(c.DefaultQuery("limit", "5"))
-	radius := parseFloatDefault(c.DefaultQuery("radius", "50"), 50)
+	var q trendingQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	lat, lon, radius, limit := *q.Lat, *q.Lon, q.Radius, q.Limit
+	if radius == 0 {
+		radius = config.Get().Trending.DefaultRadius
+	}
 
-	if lat == 0 && lon == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lon required"})
+	lang, ok := langParam(c)
+	if !ok {
 		return
 	}
-	// get trending articles from service (with caching)
-	top, err := services.GetTrendingForLocation(lat, lon, radius, limit)
+	// get trending articles from service (with caching); a language filter
+	// needs the full ranking since it is applied afterwards
+	n := limit
+	if lang != "" {
+		n = math.MaxInt32
+	}
+	top, err := services.GetTrendingForLocation(c.Request.Context(), lat, lon, radius, n)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
 	resp := []responseArticle{}
 	for _, t := range top {
+		if len(resp) >= limit {
+			break
+		}
+		if lang != "" && articleLang(t.Article) != lang {
+			continue
+		}
 		// t.Article is models.Article, t.Score is trending score
 		dist := haversine(lat, lon, t.Article.Latitude, t.Article.Longitude)
-		a := toResponseArticle(t.Article, &dist)
-		// include ranking metadata in source_name field or separate? keep consistent:
-		// attach trending score in description (not ideal) — instead add as metadata field
-		// but responseArticle fixed; add to description prefix
-		a.Description = a.Description
-		resp = append(resp, a)
+		resp = append(resp, toResponseArticle(t.Article, &dist))
 	}
+	decorateArticles(c, resp)
 	c.JSON(http.StatusOK, gin.H{"articles": resp})
 }
 
+type processQuery struct {
+	Query string `form:"query" binding:"required,max=500"`
+}
+
 // GET /api/v1/news/process?query=...
 // uses LLM to extract entities + intent
 func ProcessQuery(c *gin.Context) {
-	q := c.Query("query")
-	if q == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query required"})
+	var q processQuery
+	if !bindQuery(c, &q) || !requireText(c, "query", q.Query) {
 		return
 	}
-	out, err := services.ExtractEntitiesAndIntent(q)
+	out, err := services.ExtractEntitiesAndIntent(q.Query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		serverError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, out)
 }
 
 // utilities
-func parseLimit(s string) int {
-	n := 5
-	if s == "" {
-		return n
-	}
-	var v int
-	_, err := fmt.Sscanf(s, "%d", &v)
-	if err == nil && v > 0 {
-		n = v
-	}
-	return n
-}
-
-func parseFloatDefault(s string, d float64) float64 {
-	if s == "" {
-		return d
-	}
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return d
-	}
-	return v
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
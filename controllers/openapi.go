@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"news-backend/models"
+	"news-backend/openapi"
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// documented response bodies; the handlers build them as gin.H
+type (
+	articleList struct {
+		Articles []responseArticle `json:"articles"`
+		Total    int               `json:"total"`
+	}
+	pagedArticleList struct {
+		Articles []responseArticle `json:"articles"`
+		Total    int               `json:"total"`
+		Offset   int               `json:"offset"`
+	}
+	trendingList struct {
+		Articles []responseArticle `json:"articles"`
+	}
+	relatedList struct {
+		ArticleID string           `json:"article_id"`
+		Articles  []relatedArticle `json:"articles"`
+	}
+	storyList struct {
+		Stories []storySummary `json:"stories"`
+		Total   int            `json:"total"`
+		Offset  int            `json:"offset"`
+	}
+	storyDetail struct {
+		Story          storySummary     `json:"story"`
+		Representative *responseArticle `json:"representative"`
+		Timeline       []timelineEntry  `json:"timeline"`
+	}
+	entityList struct {
+		Entities []services.EntityCount `json:"entities"`
+	}
+	entityDetail struct {
+		Entity      models.Entity          `json:"entity"`
+		CoOccurring []services.EntityCount `json:"co_occurring"`
+	}
+	historyList struct {
+		History []historyItem `json:"history"`
+		Total   int64         `json:"total"`
+		Offset  int           `json:"offset"`
+	}
+	bookmarkList struct {
+		Bookmarks []historyItem `json:"bookmarks"`
+		Total     int64         `json:"total"`
+		Offset    int           `json:"offset"`
+	}
+	createdKey struct {
+		Key    string        `json:"key"`
+		APIKey models.APIKey `json:"api_key"`
+	}
+	keyList struct {
+		Keys  []models.APIKey `json:"keys"`
+		Total int             `json:"total"`
+	}
+	livezBody struct {
+		Status        string `json:"status"`
+		UptimeSeconds int64  `json:"uptime_seconds"`
+	}
+	readyzBody struct {
+		Status   string                 `json:"status"`
+		Mode     string                 `json:"mode"`
+		Snapshot services.SnapshotInfo  `json:"snapshot"`
+		Checks   map[string]CheckResult `json:"checks"`
+	}
+)
+
+// parameters of the article list endpoints that langParam and
+// decorateArticles read
+var articleListParams = []openapi.Parameter{
+	{Name: "lang", In: "query", Description: "only articles in this language, such as en or hi", Schema: &openapi.Schema{Type: "string"}},
+	{Name: "explain", In: "query", Description: "true adds the relevance score components", Schema: &openapi.Schema{Type: "boolean"}},
+	{Name: "Accept-Language", In: "header", Description: "translate summaries into the preferred language", Schema: &openapi.Schema{Type: "string"}},
+}
+
+// Operations documents every route, keyed by openapi.Key. A route missing
+// here fails the routes package tests and is logged at startup.
+var Operations = map[string]openapi.Operation{
+	"GET /health":       {Tag: "health", Summary: "Legacy Mongo ping", Response: HealthCheckResponse{}},
+	"GET /livez":        {Tag: "health", Summary: "Liveness: the process is up", Response: livezBody{}},
+	"GET /readyz":       {Tag: "health", Summary: "Readiness with per-dependency checks", Description: "Returns 503 while a critical check fails.", Response: readyzBody{}},
+	"GET /metrics":      {Tag: "health", Summary: "Prometheus metrics", Response: "", ContentType: "text/plain"},
+	"GET /openapi.json": {Tag: "docs", Summary: "This OpenAPI document", Response: map[string]interface{}{}},
+	"GET /docs":         {Tag: "docs", Summary: "Interactive API documentation", Response: "", ContentType: "text/html"},
+
+	"GET /api/v1/news/search": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: searchQuery{}, Response: articleList{},
+		Summary: "Full-text search", Description: "mode=hybrid fuses text matches with semantic neighbours. Accepts lang and explain=true."},
+	"GET /api/v1/news/semantic-search": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: semanticSearchQuery{}, Response: articleList{},
+		Summary: "Search by meaning using embeddings"},
+	"GET /api/v1/news/category": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: categoryQuery{}, Response: articleList{},
+		Summary: "Newest articles in a category"},
+	"GET /api/v1/news/score": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: scoreQuery{}, Response: articleList{},
+		Summary: "Articles at or above a relevance threshold"},
+	"GET /api/v1/news/source": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: sourceQuery{}, Response: articleList{},
+		Summary: "Newest articles from a source"},
+	"GET /api/v1/news/nearby": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: nearbyQuery{}, Response: articleList{},
+		Summary: "Articles within a radius, nearest first"},
+	"GET /api/v1/news/trending": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: trendingQuery{}, Response: trendingList{},
+		Summary: "Trending articles around a location"},
+	"GET /api/v1/news/live": {Tag: "news", Role: services.RoleReader, Status: http.StatusSwitchingProtocols,
+		Summary: "Live article feed over a websocket", Description: "Upgrade to a websocket and send subscribe, unsubscribe, event and ack frames."},
+	"GET /api/v1/news/articles/:id/related": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: relatedQuery{}, Response: relatedList{},
+		Summary: "Articles similar to an article"},
+	"GET /api/v1/news/feed": {Params: articleListParams, Tag: "news", Role: services.RoleReader, Query: feedQuery{}, Response: pagedArticleList{},
+		Summary: "Personal feed ranked by preferences, trending and reading history"},
+	"GET /api/v1/news/stories": {Tag: "stories", Role: services.RoleReader, Query: storiesQuery{}, Response: storyList{},
+		Summary: "Story clusters"},
+	"GET /api/v1/news/stories/:id": {Tag: "stories", Role: services.RoleReader, Response: storyDetail{},
+		Summary: "A story with its timeline"},
+	"GET /api/v1/news/entities": {Tag: "entities", Role: services.RoleReader, Query: entitiesQuery{}, Response: entityList{},
+		Summary: "Most mentioned entities"},
+	"GET /api/v1/news/entities/:id": {Tag: "entities", Role: services.RoleReader, Query: entityQuery{}, Response: entityDetail{},
+		Summary: "An entity and the entities mentioned with it"},
+	"GET /api/v1/news/entities/:id/articles": {Params: articleListParams, Tag: "entities", Role: services.RoleReader, Query: entityArticlesQuery{}, Response: pagedArticleList{},
+		Summary: "Articles mentioning an entity"},
+	"GET /api/v1/news/process": {Tag: "news", Role: services.RoleEditor, Query: processQuery{}, Response: services.QueryAnalysis{},
+		Summary: "Extract entities and intent from a query with the LLM"},
+
+	"GET /graphql": {Tag: "graphql", Role: services.RoleReader, Query: graphQLQuery{}, Response: graphQLResponse{},
+		Summary: "Run a GraphQL query", Description: "The same as POST, with variables as a JSON encoded parameter."},
+	"POST /graphql": {Tag: "graphql", Role: services.RoleReader, Body: graphQLRequest{}, Response: graphQLResponse{},
+		Summary: "Run a GraphQL query", Description: "Articles, sources, categories, trending and query analysis in one round trip. " +
+			"Errors in the query are returned with status 200 in errors, each with a problem code in extensions.code. Introspect the schema for its types."},
+
+	"GET /api/v1/users/me": {Tag: "users", Role: services.RoleReader, Response: models.User{},
+		Summary: "The caller's preferences"},
+	"PUT /api/v1/users/me": {Tag: "users", Role: services.RoleReader, Body: preferencesRequest{}, Response: models.User{},
+		Summary: "Replace the caller's preferences"},
+	"GET /api/v1/users/me/history": {Tag: "users", Role: services.RoleReader, Query: pageQuery{}, Response: historyList{},
+		Summary: "Reading history, newest first"},
+	"POST /api/v1/users/me/history": {Tag: "users", Role: services.RoleReader, Body: readRequest{}, Response: models.ReadEntry{},
+		Summary: "Record a read", Description: "The first update of a read also counts as a view for trending; updates within 30 minutes of the previous one belong to the same read."},
+	"GET /api/v1/users/me/bookmarks": {Tag: "users", Role: services.RoleReader, Query: pageQuery{}, Response: bookmarkList{},
+		Summary: "Bookmarks, newest first"},
+	"POST /api/v1/users/me/bookmarks": {Tag: "users", Role: services.RoleReader, Body: bookmarkRequest{}, Response: models.Bookmark{}, Status: http.StatusCreated,
+		Summary: "Bookmark an article"},
+	"DELETE /api/v1/users/me/bookmarks/:article_id": {Tag: "users", Role: services.RoleReader, Status: http.StatusNoContent,
+		Summary: "Remove a bookmark"},
+
+	"GET /api/v1/admin/keys": {Tag: "admin", Role: services.RoleAdmin, Response: keyList{},
+		Summary: "List API keys"},
+	"POST /api/v1/admin/keys": {Tag: "admin", Role: services.RoleAdmin, Body: createKeyRequest{}, Response: createdKey{}, Status: http.StatusCreated,
+		Summary: "Create an API key", Description: "The plaintext key is only returned in this response."},
+	"DELETE /api/v1/admin/keys/:id": {Tag: "admin", Role: services.RoleAdmin, Status: http.StatusNoContent,
+		Summary: "Revoke an API key"},
+	"GET /api/v1/admin/config": {Tag: "admin", Role: services.RoleAdmin, Response: map[string]interface{}{},
+		Summary: "Effective configuration with secrets redacted"},
+}
+
+var apiInfo = openapi.Info{
+	Title:       "News Backend API",
+	Version:     "v1",
+	Description: "Location-aware news retrieval with search, trending and personal feeds. Errors are RFC 7807 problem documents.",
+}
+
+// BuildOpenAPI generates the document for the routes of router
+func BuildOpenAPI(router *gin.Engine) *openapi.Document {
+	return openapi.Build(apiInfo, router.Routes(), Operations, problem.Details{})
+}
+
+// OpenAPISpec serves the document, generated on first use once every
+// route is registered
+// GET /openapi.json
+func OpenAPISpec(router *gin.Engine) gin.HandlerFunc {
+	var once sync.Once
+	var doc *openapi.Document
+	return func(c *gin.Context) {
+		once.Do(func() { doc = BuildOpenAPI(router) })
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// GET /docs
+// Swagger UI over /openapi.json
+func APIDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+const docsPage = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>News Backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({url: "/openapi.json", dom_id: "#ui", persistAuthorization: true});
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+
+	"news-backend/models"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storySummary adds the member count to a story
+type storySummary struct {
+	models.Story
+	Size int `json:"size"`
+}
+
+// timelineEntry is one article in a story's timeline
+type timelineEntry struct {
+	PublicationDate string          `json:"publication_date"`
+	Article         responseArticle `json:"article"`
+}
+
+type storiesQuery struct {
+	MinSize  int    `form:"min_size,default=2" binding:"min=1"`
+	Category string `form:"category"`
+	Limit    int    `form:"limit,default=10" binding:"min=1,max=100"`
+	Offset   int    `form:"offset" binding:"min=0"`
+}
+
+// GET /api/v1/news/stories?min_size=2&category=sports&limit=10&offset=0
+func ListStories(c *gin.Context) {
+	var q storiesQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	list, total := services.ListStories(q.MinSize, q.Offset, q.Limit, q.Category)
+	resp := []storySummary{}
+	for _, s := range list {
+		resp = append(resp, storySummary{Story: s, Size: len(s.Members)})
+	}
+	c.JSON(http.StatusOK, gin.H{"stories": resp, "total": total, "offset": q.Offset})
+}
+
+// GET /api/v1/news/stories/:id
+// returns the story with its representative article and a chronological timeline
+func GetStory(c *gin.Context) {
+	s, ok := services.GetStory(c.Param("id"))
+	if !ok {
+		notFound(c, "story")
+		return
+	}
+	articles, err := fetchArticlesByIDs(c.Request.Context(), s.Members)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	members := make([]models.Article, 0, len(articles))
+	for _, a := range articles {
+		members = append(members, a)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Publication.Before(members[j].Publication)
+	})
+	timeline := []timelineEntry{}
+	for _, a := range members {
+		timeline = append(timeline, timelineEntry{PublicationDate: a.PublicationRaw, Article: toResponseArticle(a, nil)})
+	}
+	var representative *responseArticle
+	if a, ok := articles[s.RepresentativeID]; ok {
+		ra := toResponseArticle(a, nil)
+		representative = &ra
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"story":          storySummary{Story: s, Size: len(s.Members)},
+		"representative": representative,
+		"timeline":       timeline,
+	})
+}
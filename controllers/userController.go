@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"news-backend/middleware"
+	"news-backend/models"
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUserID returns the principal subject used as the user ID
+func currentUserID(c *gin.Context) (string, bool) {
+	p, ok := middleware.CurrentPrincipal(c)
+	if !ok || p.Subject == "" {
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "an authenticated user is required")
+		return "", false
+	}
+	return p.Subject, true
+}
+
+type preferencesRequest struct {
+	PreferredCategories []string `json:"preferred_categories"`
+	FollowedSources     []string `json:"followed_sources"`
+	MutedSources        []string `json:"muted_sources"`
+	HomeLatitude        float64  `json:"home_latitude" binding:"gte=-90,lte=90"`
+	HomeLongitude       float64  `json:"home_longitude" binding:"gte=-180,lte=180"`
+	Language            string   `json:"language" binding:"max=16"`
+}
+
+type feedQuery struct {
+	Limit  int `form:"limit,default=10" binding:"min=1,max=100"`
+	Offset int `form:"offset" binding:"min=0"`
+}
+
+// GET /api/v1/users/me
+func GetMyPreferences(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	u, err := services.GetUser(c.Request.Context(), id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// PUT /api/v1/users/me
+func UpdateMyPreferences(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var req preferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	u, err := services.SaveUser(c.Request.Context(), models.User{
+		ID:                  id,
+		PreferredCategories: trimAll(req.PreferredCategories),
+		FollowedSources:     trimAll(req.FollowedSources),
+		MutedSources:        trimAll(req.MutedSources),
+		HomeLatitude:        req.HomeLatitude,
+		HomeLongitude:       req.HomeLongitude,
+		Language:            strings.ToLower(strings.TrimSpace(req.Language)),
+	})
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// GET /api/v1/news/feed?limit=10&offset=0&lang=hi
+func GetFeed(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	lang, ok := langParam(c)
+	if !ok {
+		return
+	}
+	var q feedQuery
+	if !bindQuery(c, &q) {
+		return
+	}
+	limit, offset := q.Limit, q.Offset
+
+	u, err := services.GetUser(ctx, id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	read, err := services.ReadArticleIDs(ctx, id)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	articles, err := fetchAllArticles(ctx)
+	if err != nil {
+		serverError(c, err)
+		return
+	}
+	var trending map[string]float64
+	if u.HasHome() {
+		trending = services.TrendingScoresNear(ctx, u.HomeLatitude, u.HomeLongitude, services.FeedTrendingRadius)
+	}
+	items := services.RankFeed(filterLang(articles, lang), u, trending, read)
+	resp := []responseArticle{}
+	for i := offset; i < min(offset+limit, len(items)); i++ {
+		resp = append(resp, toResponseArticle(items[i].Article, nil))
+	}
+	decorateArticles(c, resp)
+	c.JSON(http.StatusOK, gin.H{"articles": resp, "total": len(items), "offset": offset})
+}
+
+func trimAll(values []string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
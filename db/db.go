@@ -0,0 +1,92 @@
+// Package db owns the one MongoDB client of the process. Controllers,
+// services and background jobs all share its connection pool; nothing else
+// should call mongo.Connect.
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"news-backend/config"
+	"news-backend/metrics"
+	"news-backend/tracing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	mu     sync.RWMutex
+	client *mongo.Client
+	dbName string
+)
+
+// ErrNotConnected is returned by Disconnect before Connect was called
+var ErrNotConnected = errors.New("mongo client not connected")
+
+// Options builds the client options from the mongo config section: URI,
+// pool sizes, timeouts, and the metrics and tracing command monitors
+func Options(cfg config.MongoConfig) *options.ClientOptions {
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetConnectTimeout(cfg.ConnectTimeout.D()).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout.D()).
+		SetMaxPoolSize(uint64(cfg.MaxPoolSize)).
+		SetMinPoolSize(uint64(cfg.MinPoolSize)).
+		SetMaxConnIdleTime(cfg.MaxConnIdleTime.D()).
+		SetMonitor(tracing.MongoMonitor(metrics.MongoMonitor()))
+	if cfg.OperationTimeout > 0 {
+		opts.SetTimeout(cfg.OperationTimeout.D())
+	}
+	return opts
+}
+
+// Connect creates the shared client. It only validates the options: the
+// driver dials in the background and reconnects by itself after outages, so
+// callers ping to find out whether the server is reachable. Connecting twice
+// returns the existing client.
+func Connect(ctx context.Context, cfg config.MongoConfig) (*mongo.Client, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+	c, err := mongo.Connect(ctx, Options(cfg))
+	if err != nil {
+		return nil, err
+	}
+	client, dbName = c, cfg.Database
+	return client, nil
+}
+
+// Client returns the shared client, or nil before Connect
+func Client() *mongo.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// Database returns the configured database on the shared client, or nil
+// before Connect
+func Database() *mongo.Database {
+	mu.RLock()
+	defer mu.RUnlock()
+	if client == nil {
+		return nil
+	}
+	return client.Database(dbName)
+}
+
+// Disconnect closes the pool, waiting for in-use connections until ctx is
+// done. The client cannot be used afterwards.
+func Disconnect(ctx context.Context) error {
+	mu.Lock()
+	c := client
+	client = nil
+	mu.Unlock()
+	if c == nil {
+		return ErrNotConnected
+	}
+	return c.Disconnect(ctx)
+}
@@ -0,0 +1,113 @@
+// Package logging configures the process-wide log/slog logger. Records logged
+// with a context carry the request ID and, when tracing is on, the trace and
+// span IDs, so a log line can be matched to its request and trace.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs a JSON (or text) slog logger at the given level as the
+// default. The standard log package is redirected to it, so any remaining
+// log.Print output is structured too.
+func Setup(w io.Writer, format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("log format %q must be json or text", format)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	return nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log records carry id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds request and trace IDs from the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// Fatal logs at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Go runs a background job in its own goroutine. A returned error or a panic
+// is logged with the job name, so failures are never silently dropped.
+func Go(ctx context.Context, job string, fn func(context.Context) error) {
+	go func() {
+		_ = Run(ctx, job, fn)
+	}()
+}
+
+// Run runs a job in the calling goroutine with the same failure logging as Go
+func Run(ctx context.Context, job string, fn func(context.Context) error) (err error) {
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+			slog.ErrorContext(ctx, "background job panicked", "job", job, "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+			return
+		}
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			// stopped by shutdown rather than failed
+			slog.InfoContext(ctx, "background job cancelled", "job", job, "duration_ms", time.Since(start).Milliseconds())
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "background job failed", "job", job, "error", err, "duration_ms", time.Since(start).Milliseconds())
+			return
+		}
+		slog.DebugContext(ctx, "background job finished", "job", job, "duration_ms", time.Since(start).Milliseconds())
+	}()
+	return fn(ctx)
+}
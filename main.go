@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"news-backend/config"
 	"news-backend/controllers"
+	"news-backend/db"
+	"news-backend/logging"
+	"news-backend/middleware"
+	"news-backend/openapi"
 	"news-backend/routes"
+	"news-backend/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -19,45 +27,74 @@ import (
 func main() {
 	_ = godotenv.Load()
 
-	// Set Gin mode
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
-	} else {
-		gin.SetMode(gin.DebugMode)
+	// defaults < config file < environment < flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("config: ", err)
+	}
+	if err := logging.Setup(os.Stdout, cfg.Log.Format, cfg.Log.Level); err != nil {
+		log.Fatal("logging: ", err)
 	}
 
+	// Set Gin mode
+	gin.SetMode(cfg.Server.Mode)
+
 	// Create a context that listens for the interrupt signal
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	// connect DB and seed articles
-	controllers.ConnectDB()
-	controllers.SaveArticlesToDB()
-
-	router := gin.New()
-
-	// Middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	// tracing goes first so startup database calls are traced too
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		logging.Fatal("tracing", "error", err)
+	}
 
-	// Health check endpoint
-	router.GET("/health", controllers.HealthCheck)
+	// connect, migrate and seed in the background so /livez answers while
+	// MongoDB is still unreachable; reads are served from a snapshot meanwhile
+	logging.Go(ctx, "startup", func(ctx context.Context) error {
+		if err := controllers.ConnectDB(ctx); err != nil {
+			return err
+		}
+		controllers.SaveArticlesToDB(ctx)
+		controllers.StartRelevanceScorer(ctx)
+		controllers.StartImportWatcher(ctx)
+		go controllers.MonitorDB(ctx)
+		return nil
+	})
+
+	// share rate limit state across replicas when a Redis-protocol server is configured
+	if url := cfg.RateLimit.RedisURL; url != "" {
+		rctx, rcancel := context.WithTimeout(context.Background(), 5*time.Second)
+		rs, err := middleware.NewRedisStore(rctx, url)
+		rcancel()
+		if err != nil {
+			slog.Warn("rate limit redis unavailable, falling back to in-memory store", "error", err)
+		} else {
+			middleware.SetRateLimitStore(rs)
+			defer rs.Close()
+		}
+	}
 
-	// API routes
-	routes.SetupRoutes(router)
+	router := routes.NewRouter()
+	if missing := openapi.Missing(router.Routes(), controllers.Operations); len(missing) > 0 {
+		slog.Warn("routes missing from the OpenAPI spec", "routes", missing)
+	}
 
 	// Create a server with timeouts
 	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
+		Addr:         cfg.Server.Addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout.D(),
+		WriteTimeout: cfg.Server.WriteTimeout.D(),
+		IdleTimeout:  cfg.Server.IdleTimeout.D(),
 	}
 
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling
 	go func() {
-		log.Printf("Server is running on http://localhost:8080")
+		slog.Info("server listening", "addr", cfg.Server.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
+			logging.Fatal("listen", "error", err)
 		}
 	}()
 
@@ -66,15 +103,23 @@ func main() {
 
 	// Restore default behavior on the interrupt signal
 	stop()
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// The context is used to inform the server it has server.shutdown_timeout
+	// to finish the request it is currently handling
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.D())
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		logging.Fatal("server forced to shutdown", "error", err)
+	}
+	// close the shared Mongo pool once in-flight requests are done
+	if err := db.Disconnect(ctx); err != nil && !errors.Is(err, db.ErrNotConnected) {
+		slog.ErrorContext(ctx, "mongo disconnect", "error", err)
+	}
+	// flush spans still buffered for export
+	if err := shutdownTracing(ctx); err != nil {
+		slog.ErrorContext(ctx, "tracing shutdown", "error", err)
 	}
 
-	log.Println("Server exiting")
+	slog.Info("server exiting")
 }
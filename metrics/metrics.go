@@ -0,0 +1,122 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics. The
+// default registry is used, so Go runtime and process metrics are included.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	// route is the gin route pattern (c.FullPath()), never the raw path, so
+	// IDs in URLs do not create new series
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_http_requests_total",
+		Help: "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "news_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "news_mongo_command_duration_seconds",
+		Help:    "MongoDB command latency by command name.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"command"})
+	mongoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_mongo_command_errors_total",
+		Help: "Failed MongoDB commands by command name.",
+	}, []string{"command"})
+
+	trendingCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_trending_cache_requests_total",
+		Help: "Trending cache lookups by result (hit or miss).",
+	}, []string{"result"})
+
+	eventsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_events_ingested_total",
+		Help: "User events recorded for trending, by event type.",
+	}, []string{"type"})
+
+	llmDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "news_llm_request_duration_seconds",
+		Help:    "LLM and embeddings provider call latency by operation and model.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"operation", "model"})
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_llm_tokens_total",
+		Help: "Tokens reported by the LLM provider, by operation, model and kind (prompt or completion).",
+	}, []string{"operation", "model", "kind"})
+	llmFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "news_llm_request_failures_total",
+		Help: "Failed LLM and embeddings provider calls by operation and model.",
+	}, []string{"operation", "model"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTP records one finished request. An empty route means no route matched.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// MongoMonitor returns a command monitor that records latency and errors for
+// every command a client sends; pass it to options.Client().SetMonitor
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+			mongoErrors.WithLabelValues(e.CommandName).Inc()
+		},
+	}
+}
+
+// TrendingCache records a trending cache lookup
+func TrendingCache(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	trendingCache.WithLabelValues(result).Inc()
+}
+
+// EventIngested counts one recorded user event
+func EventIngested(eventType string) {
+	eventsIngested.WithLabelValues(eventType).Inc()
+}
+
+// LLMCall records one provider call. Token counts of zero (not reported by
+// the provider) are not added.
+func LLMCall(operation, model string, d time.Duration, promptTokens, completionTokens int, err error) {
+	llmDuration.WithLabelValues(operation, model).Observe(d.Seconds())
+	if err != nil && !errors.Is(err, context.Canceled) {
+		llmFailures.WithLabelValues(operation, model).Inc()
+	}
+	if promptTokens > 0 {
+		llmTokens.WithLabelValues(operation, model, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		llmTokens.WithLabelValues(operation, model, "completion").Add(float64(completionTokens))
+	}
+}
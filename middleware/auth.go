@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalKey is the gin context key holding the authenticated services.Principal
+const PrincipalKey = "principal"
+
+// RequireRole authenticates the request with an API key (X-API-Key header or
+// "Authorization: Bearer <key>") or a JWT bearer token, and rejects callers
+// whose role is below the given one.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := c.Get(PrincipalKey)
+		if !ok {
+			principal, err := authenticate(c)
+			if err != nil {
+				c.Header("WWW-Authenticate", `Bearer realm="news-backend"`)
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, err.Error())
+				return
+			}
+			c.Set(PrincipalKey, principal)
+			p = principal
+		}
+		principal := p.(services.Principal)
+		if !services.RoleAllows(principal.Role, role) {
+			problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, "this route requires the "+role+" role")
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentPrincipal returns the authenticated caller, if any
+func CurrentPrincipal(c *gin.Context) (services.Principal, bool) {
+	p, ok := c.Get(PrincipalKey)
+	if !ok {
+		return services.Principal{}, false
+	}
+	principal, ok := p.(services.Principal)
+	return principal, ok
+}
+
+func authenticate(c *gin.Context) (services.Principal, error) {
+	token := c.GetHeader("X-API-Key")
+	if token == "" {
+		auth := c.GetHeader("Authorization")
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			token = strings.TrimSpace(auth[7:])
+		}
+	}
+	if token == "" {
+		return services.Principal{}, errors.New("missing credentials: send X-API-Key or Authorization: Bearer")
+	}
+	// JWTs have three dot-separated segments, API keys have none
+	if strings.Count(token, ".") == 2 {
+		return services.VerifyJWT(token)
+	}
+	p, err := services.AuthenticateAPIKey(c.Request.Context(), token)
+	if err != nil && !errors.Is(err, services.ErrInvalidCredentials) {
+		slog.ErrorContext(c.Request.Context(), "api key lookup", "error", err)
+		return services.Principal{}, errors.New("unable to verify credentials")
+	}
+	return p, err
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"news-backend/problem"
+	"news-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routes that only read even when they take a POST body
+var readOnlyPosts = map[string]bool{"/graphql": true}
+
+// ReadOnlyWhenDegraded rejects writes with 503 while the database is
+// unavailable; reads go through and are served from the article snapshot.
+func ReadOnlyWhenDegraded() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !services.DatabaseAvailable() && !(c.Request.Method == http.MethodPost && readOnlyPosts[c.FullPath()]) {
+				unavailable(c, "service is read-only while the database is unavailable")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireDatabase rejects requests with 503 while the database is
+// unavailable, for reads that have no snapshot fallback
+func RequireDatabase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.DatabaseAvailable() {
+			unavailable(c, "database unavailable")
+			return
+		}
+		c.Next()
+	}
+}
+
+func unavailable(c *gin.Context, msg string) {
+	problem.Unavailable(c, 30, msg)
+}
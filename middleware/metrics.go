@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"time"
+
+	"news-backend/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records request count and latency labelled by route pattern
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTP(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"news-backend/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RatePolicy is the budget applied to a route group. Every client gets its own
+// token bucket refilling at Rate requests/s up to Burst, plus a DailyQuota
+// (0 disables the quota).
+type RatePolicy struct {
+	Name       string
+	Rate       float64
+	Burst      int
+	DailyQuota int
+}
+
+// Default budgets. Search scans every article and process will call a paid
+// LLM, so both are stricter than plain listing routes. AuthPolicy runs before
+// authentication, so it is keyed by IP only and caps credential guessing.
+var (
+	AuthPolicy    = RatePolicy{Name: "auth", Rate: 20, Burst: 40}
+	DefaultPolicy = RatePolicy{Name: "default", Rate: 10, Burst: 20, DailyQuota: 20000}
+	SearchPolicy  = RatePolicy{Name: "search", Rate: 2, Burst: 5, DailyQuota: 2000}
+	ProcessPolicy = RatePolicy{Name: "process", Rate: 0.5, Burst: 2, DailyQuota: 200}
+)
+
+var (
+	storeMu sync.RWMutex
+	store   RateLimitStore = NewMemoryStore()
+)
+
+// SetRateLimitStore replaces the store shared by all rate limit middleware
+func SetRateLimitStore(s RateLimitStore) {
+	storeMu.Lock()
+	store = s
+	storeMu.Unlock()
+}
+
+func currentStore() RateLimitStore {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// RateLimit enforces the policy per client IP and, when the request is
+// authenticated, per principal as well. Register it after RequireRole so the
+// principal is known, except for AuthPolicy, which goes before it. Store failures are logged and the request let through.
+func RateLimit(p RatePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		s := currentStore()
+
+		// identity used for the quota: the principal if known, otherwise the IP
+		identity := "ip:" + c.ClientIP()
+		keys := []string{identity}
+		if principal, ok := CurrentPrincipal(c); ok {
+			identity = "sub:" + principal.Subject
+			keys = append(keys, identity)
+		}
+
+		var tightest *BucketResult
+		for _, k := range keys {
+			res, err := s.Take(ctx, p.Name+":"+k, p.Rate, p.Burst)
+			if err != nil {
+				slog.ErrorContext(ctx, "rate limit store", "error", err)
+				c.Next()
+				return
+			}
+			if tightest == nil || !res.Allowed || res.Remaining < tightest.Remaining {
+				r := res
+				tightest = &r
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(p.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.ResetAfter)))
+		if !tightest.Allowed {
+			tooMany(c, tightest.RetryAfter, fmt.Sprintf("rate limit of %g requests/s exceeded", p.Rate))
+			return
+		}
+
+		if p.DailyQuota > 0 {
+			now := clock().UTC()
+			midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+			untilReset := midnight.Sub(now)
+			key := fmt.Sprintf("quota:%s:%s:%s", p.Name, identity, now.Format("2006-01-02"))
+			used, err := s.IncrDaily(ctx, key, untilReset+time.Hour)
+			if err != nil {
+				slog.ErrorContext(ctx, "rate limit store", "error", err)
+				c.Next()
+				return
+			}
+			remaining := int64(p.DailyQuota) - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			c.Header("X-RateLimit-Quota-Limit", strconv.Itoa(p.DailyQuota))
+			c.Header("X-RateLimit-Quota-Remaining", strconv.FormatInt(remaining, 10))
+			c.Header("X-RateLimit-Quota-Reset", strconv.Itoa(ceilSeconds(untilReset)))
+			if used > int64(p.DailyQuota) {
+				tooMany(c, untilReset, fmt.Sprintf("daily quota of %d requests exceeded", p.DailyQuota))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func tooMany(c *gin.Context, retry time.Duration, msg string) {
+	c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(retry))))
+	problem.Abort(c, http.StatusTooManyRequests, problem.CodeRateLimited, msg)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// BucketResult is the outcome of taking one token from a bucket
+type BucketResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token is available when denied
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets and daily quota counters. Implementations
+// must be safe for concurrent use; the Redis store shares state across replicas.
+type RateLimitStore interface {
+	// Take removes one token from the bucket at key, refilling at rate tokens/s up to burst
+	Take(ctx context.Context, key string, rate float64, burst int) (BucketResult, error)
+	// IncrDaily increments the counter at key and returns the new value. Keys
+	// are scoped to a day, so the counter only needs to outlive it by ttl.
+	IncrDaily(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// clock is the time source of the rate limiter, replaced in tests
+var clock = time.Now
+
+func bucketTimes(tokens, rate float64, burst int) (retry, reset time.Duration) {
+	if tokens < 1 {
+		retry = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	reset = time.Duration((float64(burst) - tokens) / rate * float64(time.Second))
+	return retry, reset
+}
+
+// MemoryStore is a single-instance RateLimitStore
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memBucket
+	counters  map[string]*memCounter
+	lastSweep time.Time
+}
+
+type memBucket struct {
+	tokens float64
+	ts     time.Time
+	idle   time.Duration
+}
+
+type memCounter struct {
+	n       int64
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*memBucket{},
+		counters:  map[string]*memCounter{},
+		lastSweep: clock(),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, rate float64, burst int) (BucketResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clock()
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memBucket{tokens: float64(burst), ts: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.ts).Seconds()*rate)
+	b.ts = now
+	// a bucket idle long enough to refill completely can be forgotten
+	b.idle = time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+	res := BucketResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	}
+	res.Remaining = int(b.tokens)
+	res.RetryAfter, res.ResetAfter = bucketTimes(b.tokens, rate, burst)
+	return res, nil
+}
+
+func (s *MemoryStore) IncrDaily(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clock()
+	c, ok := s.counters[key]
+	if !ok || now.After(c.expires) {
+		c = &memCounter{expires: now.Add(ttl)}
+		s.counters[key] = c
+	}
+	c.n++
+	return c.n, nil
+}
+
+// sweep drops full buckets and expired counters at most once a minute
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if now.Sub(b.ts) > b.idle {
+			delete(s.buckets, k)
+		}
+	}
+	for k, c := range s.counters {
+		if now.After(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+}
+
+// RedisStore is a RateLimitStore backed by any server speaking the Redis protocol
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to the server at url (redis://[:password@]host:port/db)
+func NewRedisStore(ctx context.Context, url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &RedisStore{client: client, prefix: "ratelimit:"}, nil
+}
+
+// Close releases the underlying connection pool
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// takeScript refills and takes from a bucket atomically. Tokens are returned as
+// a string because Redis truncates Lua numbers to integers.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+func (s *RedisStore) Take(ctx context.Context, key string, rate float64, burst int) (BucketResult, error) {
+	vals, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		rate, burst, clock().UnixMilli()).Slice()
+	if err != nil {
+		return BucketResult{}, err
+	}
+	allowed, _ := vals[0].(int64)
+	tokensStr, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return BucketResult{}, err
+	}
+	res := BucketResult{Allowed: allowed == 1, Remaining: int(tokens)}
+	res.RetryAfter, res.ResetAfter = bucketTimes(tokens, rate, burst)
+	return res, nil
+}
+
+func (s *RedisStore) IncrDaily(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	k := s.prefix + key
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, k)
+	pipe.Expire(ctx, k, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
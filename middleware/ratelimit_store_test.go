@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// fakeClock replaces clock for the test and returns a function moving it forward
+func fakeClock(t *testing.T, start time.Time) func(time.Duration) {
+	now := start
+	clock = func() time.Time { return now }
+	t.Cleanup(func() { clock = time.Now })
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+// a bucket refilling at 1 token/s up to 2, taken from after each advance
+var takeSteps = []struct {
+	name      string
+	advance   time.Duration
+	allowed   bool
+	remaining int
+	retry     time.Duration
+}{
+	{"starts full", 0, true, 1, 0},
+	{"takes the last token", 0, true, 0, time.Second},
+	{"denies when empty", 0, false, 0, time.Second},
+	{"half a token is not enough", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+	{"refills to one token", 500 * time.Millisecond, true, 0, time.Second},
+	{"refill stops at burst", 10 * time.Second, true, 1, 0},
+}
+
+func testTake(t *testing.T, s RateLimitStore) {
+	advance := fakeClock(t, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	for _, step := range takeSteps {
+		advance(step.advance)
+		res, err := s.Take(context.Background(), "test", 1, 2)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if res.Allowed != step.allowed || res.Remaining != step.remaining {
+			t.Errorf("%s: allowed=%v remaining=%d, want allowed=%v remaining=%d",
+				step.name, res.Allowed, res.Remaining, step.allowed, step.remaining)
+		}
+		if res.RetryAfter != step.retry {
+			t.Errorf("%s: retry after %v, want %v", step.name, res.RetryAfter, step.retry)
+		}
+	}
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	testTake(t, NewMemoryStore())
+}
+
+// the Lua script must refill and take like the memory store
+func TestRedisStoreTake(t *testing.T) {
+	s, _ := newTestRedisStore(t)
+	testTake(t, s)
+}
+
+func TestMemoryStoreIncrDailyExpires(t *testing.T) {
+	advance := fakeClock(t, time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC))
+	s := NewMemoryStore()
+	ctx := context.Background()
+	for want := int64(1); want <= 3; want++ {
+		if n, _ := s.IncrDaily(ctx, "quota", time.Hour); n != want {
+			t.Fatalf("count %d, want %d", n, want)
+		}
+	}
+	advance(time.Hour + time.Second)
+	if n, _ := s.IncrDaily(ctx, "quota", time.Hour); n != 1 {
+		t.Fatalf("count after expiry %d, want 1", n)
+	}
+}
+
+func TestRedisStoreIncrDailyExpires(t *testing.T) {
+	s, mr := newTestRedisStore(t)
+	ctx := context.Background()
+	for want := int64(1); want <= 3; want++ {
+		if n, err := s.IncrDaily(ctx, "quota", time.Hour); err != nil || n != want {
+			t.Fatalf("count %d (%v), want %d", n, err, want)
+		}
+	}
+	mr.FastForward(time.Hour + time.Second)
+	if n, err := s.IncrDaily(ctx, "quota", time.Hour); err != nil || n != 1 {
+		t.Fatalf("count after expiry %d (%v), want 1", n, err)
+	}
+}
+
+// newTestRedisStore connects a RedisStore to an in-process miniredis server
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	s, err := NewRedisStore(context.Background(), "redis://"+mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, mr
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the daily quota runs out and comes back at midnight UTC
+func TestRateLimitDailyQuotaResets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	advance := fakeClock(t, time.Date(2026, 1, 1, 23, 59, 58, 0, time.UTC))
+	SetRateLimitStore(NewMemoryStore())
+	t.Cleanup(func() { SetRateLimitStore(NewMemoryStore()) })
+
+	router := gin.New()
+	router.Use(RateLimit(RatePolicy{Name: "test", Rate: 100, Burst: 100, DailyQuota: 2}))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	steps := []struct {
+		name       string
+		advance    time.Duration
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{"first request", 0, http.StatusOK, "1", ""},
+		{"uses up the quota", 0, http.StatusOK, "0", ""},
+		{"over quota until midnight", 0, http.StatusTooManyRequests, "0", "2"},
+		{"next day", 3 * time.Second, http.StatusOK, "1", ""},
+	}
+	for _, step := range steps {
+		advance(step.advance)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != step.status {
+			t.Errorf("%s: status %d, want %d", step.name, w.Code, step.status)
+		}
+		if got := w.Header().Get("X-RateLimit-Quota-Remaining"); got != step.remaining {
+			t.Errorf("%s: quota remaining %q, want %q", step.name, got, step.remaining)
+		}
+		if got := w.Header().Get("Retry-After"); got != step.retryAfter {
+			t.Errorf("%s: Retry-After %q, want %q", step.name, got, step.retryAfter)
+		}
+	}
+}
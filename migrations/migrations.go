@@ -0,0 +1,230 @@
+// Package migrations applies versioned schema changes to the news database.
+// Applied versions are recorded in the schema_migrations collection and a
+// lease in migration_lock makes sure only one replica migrates at a time.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is one schema change to the articles collection. Versions are
+// unique and applied in ascending order; Down may be nil for changes that
+// cannot be reverted.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, articles *mongo.Collection) error
+	Down    func(ctx context.Context, articles *mongo.Collection) error
+}
+
+// Status is a migration with whether and when it was applied
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type appliedMigration struct {
+	Version    int       `bson:"_id"`
+	Name       string    `bson:"name"`
+	AppliedAt  time.Time `bson:"applied_at"`
+	DurationMS int64     `bson:"duration_ms"`
+}
+
+const (
+	appliedCollection = "schema_migrations"
+	lockCollection    = "migration_lock"
+	lockID            = "lock"
+	// the lease is renewed while migrating, so it only expires if the holder died
+	lockLease = time.Minute
+	// how long to wait for another replica to finish migrating
+	lockWait = 10 * time.Minute
+)
+
+// ErrIrreversible is returned by Down for a migration without a Down step
+var ErrIrreversible = errors.New("migration cannot be reverted")
+
+func sorted() []Migration {
+	out := append([]Migration{}, registry...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// Latest returns the highest known version
+func Latest() int {
+	all := sorted()
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+func applied(ctx context.Context, db *mongo.Database) (map[int]appliedMigration, error) {
+	cur, err := db.Collection(appliedCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := map[int]appliedMigration{}
+	for cur.Next(ctx) {
+		var m appliedMigration
+		if err := cur.Decode(&m); err != nil {
+			return nil, err
+		}
+		out[m.Version] = m
+	}
+	return out, cur.Err()
+}
+
+// List reports every known migration and whether it has been applied
+func List(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	out := []Status{}
+	for _, m := range sorted() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if a, ok := done[m.Version]; ok {
+			t := a.AppliedAt
+			s.AppliedAt = &t
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// Up applies pending migrations to the named articles collection up to and
+// including target, or all of them when target is 0, and returns the
+// versions applied.
+func Up(ctx context.Context, db *mongo.Database, articles string, target int) ([]int, error) {
+	ran := []int{}
+	err := withLock(ctx, db, func(ctx context.Context) error {
+		done, err := applied(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, m := range sorted() {
+			if target > 0 && m.Version > target {
+				break
+			}
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			slog.InfoContext(ctx, "migration up", "version", m.Version, "name", m.Name)
+			start := time.Now()
+			if err := m.Up(ctx, db.Collection(articles)); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+			rec := appliedMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC(), DurationMS: time.Since(start).Milliseconds()}
+			if _, err := db.Collection(appliedCollection).InsertOne(ctx, rec); err != nil {
+				return err
+			}
+			ran = append(ran, m.Version)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts applied migrations above target, newest first, and returns
+// the versions reverted. target 0 reverts everything.
+func Down(ctx context.Context, db *mongo.Database, articles string, target int) ([]int, error) {
+	ran := []int{}
+	err := withLock(ctx, db, func(ctx context.Context) error {
+		done, err := applied(ctx, db)
+		if err != nil {
+			return err
+		}
+		all := sorted()
+		for i := len(all) - 1; i >= 0; i-- {
+			m := all[i]
+			if m.Version <= target {
+				break
+			}
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, ErrIrreversible)
+			}
+			slog.InfoContext(ctx, "migration down", "version", m.Version, "name", m.Name)
+			if err := m.Down(ctx, db.Collection(articles)); err != nil {
+				return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+			}
+			if _, err := db.Collection(appliedCollection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+				return err
+			}
+			ran = append(ran, m.Version)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// withLock runs fn while holding the migration lease, waiting for another
+// holder to finish. The lease is renewed in the background until fn returns.
+func withLock(ctx context.Context, db *mongo.Database, fn func(context.Context) error) error {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s/%d/%d", host, os.Getpid(), time.Now().UnixNano())
+	locks := db.Collection(lockCollection)
+
+	deadline := time.Now().Add(lockWait)
+	for {
+		now := time.Now().UTC()
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(lockLease)}},
+			options.Update().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		// a live lease makes the upsert collide with the existing lock document
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for the migration lock")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	renewCtx, stopRenew := context.WithCancel(context.Background())
+	defer func() {
+		stopRenew()
+		if _, err := locks.DeleteOne(context.Background(), bson.M{"_id": lockID, "owner": owner}); err != nil {
+			slog.ErrorContext(ctx, "release migration lock", "error", err)
+		}
+	}()
+	go func() {
+		t := time.NewTicker(lockLease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-t.C:
+				if _, err := locks.UpdateOne(renewCtx,
+					bson.M{"_id": lockID, "owner": owner},
+					bson.M{"$set": bson.M{"expires_at": time.Now().UTC().Add(lockLease)}}); err != nil {
+					slog.ErrorContext(ctx, "renew migration lock", "error", err)
+				}
+			}
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// a live lease held by another replica answers the lock upsert like this
+var leaseHeld = mtest.CreateWriteErrorsResponse(mtest.WriteError{
+	Index: 0, Code: 11000, Message: "E11000 duplicate key error collection: news.migration_lock",
+})
+
+func TestWithLock(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("runs fn once the lease is taken", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		ran := false
+		err := withLock(context.Background(), mt.DB, func(context.Context) error {
+			ran = true
+			return nil
+		})
+		if err != nil || !ran {
+			mt.Fatalf("withLock = %v, ran %v", err, ran)
+		}
+		// the lease is released for the next replica
+		if ev := mt.GetStartedEvent(); ev == nil || ev.CommandName != "update" {
+			mt.Fatalf("first command %v, want update", ev)
+		}
+		if ev := mt.GetStartedEvent(); ev == nil || ev.CommandName != "delete" {
+			mt.Fatalf("second command %v, want delete", ev)
+		}
+	})
+
+	mt.Run("waits while another replica holds the lease", func(mt *mtest.T) {
+		mt.AddMockResponses(leaseHeld)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		ran := false
+		err := withLock(ctx, mt.DB, func(context.Context) error {
+			ran = true
+			return nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) || ran {
+			mt.Fatalf("withLock = %v, ran %v; want to give up waiting without running fn", err, ran)
+		}
+	})
+
+	mt.Run("takes the lease when the holder releases it", func(mt *mtest.T) {
+		mt.AddMockResponses(leaseHeld, mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+		ran := false
+		err := withLock(context.Background(), mt.DB, func(context.Context) error {
+			ran = true
+			return nil
+		})
+		if err != nil || !ran {
+			mt.Fatalf("withLock = %v, ran %v", err, ran)
+		}
+	})
+
+	mt.Run("returns other errors at once", func(mt *mtest.T) {
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 0}, {Key: "code", Value: 13}, {Key: "errmsg", Value: "unauthorized"}})
+		err := withLock(context.Background(), mt.DB, func(context.Context) error {
+			mt.Fatal("fn ran without the lease")
+			return nil
+		})
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 13 {
+			mt.Fatalf("withLock = %v, want the unauthorized error", err)
+		}
+	})
+}
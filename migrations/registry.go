@@ -0,0 +1,175 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+
+	"news-backend/models"
+	"news-backend/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// registry lists every migration; append new ones with the next version
+var registry = []Migration{
+	{
+		Version: 1,
+		Name:    "article_canonical_url",
+		Up: func(ctx context.Context, articles *mongo.Collection) error {
+			return rewriteArticles(ctx, articles, bson.M{"canonical_url": bson.M{"$exists": false}}, func(a models.Article) bson.M {
+				if u := services.CanonicalURL(a.URL); u != "" {
+					return bson.M{"canonical_url": u}
+				}
+				return nil
+			})
+		},
+		Down: func(ctx context.Context, articles *mongo.Collection) error {
+			return unsetArticleField(ctx, articles, "canonical_url")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "article_published_at",
+		Up: func(ctx context.Context, articles *mongo.Collection) error {
+			err := rewriteArticles(ctx, articles, bson.M{"published_at": bson.M{"$exists": false}}, func(a models.Article) bson.M {
+				if t := models.ParsePublication(a.PublicationRaw); !t.IsZero() {
+					return bson.M{"published_at": t}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			_, err = articles.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "published_at", Value: -1}},
+				Options: options.Index().SetName("published_at_-1"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, articles *mongo.Collection) error {
+			if err := dropIndex(ctx, articles, "published_at_-1"); err != nil {
+				return err
+			}
+			return unsetArticleField(ctx, articles, "published_at")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "article_geojson_location",
+		Up: func(ctx context.Context, articles *mongo.Collection) error {
+			// an update pipeline builds the point server-side from the stored
+			// coordinates; articles without valid numeric ones get no point,
+			// since a null or out-of-range coordinate fails the 2dsphere index build
+			_, err := articles.UpdateMany(ctx,
+				bson.M{
+					"location":  bson.M{"$exists": false},
+					"latitude":  bson.M{"$type": "number", "$gte": -90, "$lte": 90},
+					"longitude": bson.M{"$type": "number", "$gte": -180, "$lte": 180},
+				},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{
+					"location": bson.M{"type": "Point", "coordinates": bson.A{"$longitude", "$latitude"}},
+				}}}})
+			if err != nil {
+				return err
+			}
+			_, err = articles.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "location", Value: "2dsphere"}},
+				Options: options.Index().SetName("location_2dsphere"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, articles *mongo.Collection) error {
+			if err := dropIndex(ctx, articles, "location_2dsphere"); err != nil {
+				return err
+			}
+			return unsetArticleField(ctx, articles, "location")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "article_entities_published_at_index",
+		Up: func(ctx context.Context, articles *mongo.Collection) error {
+			// entity pages sort on published_at; the raw date strings do not sort across formats
+			_, err := articles.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "entities", Value: 1}, {Key: "published_at", Value: -1}},
+				Options: options.Index().SetName("entities_1_published_at_-1"),
+			})
+			if err != nil {
+				return err
+			}
+			return dropIndex(ctx, articles, "entities_1_publication_date_-1")
+		},
+		Down: func(ctx context.Context, articles *mongo.Collection) error {
+			_, err := articles.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "entities", Value: 1}, {Key: "publication_date", Value: -1}},
+				Options: options.Index().SetName("entities_1_publication_date_-1"),
+			})
+			if err != nil {
+				return err
+			}
+			return dropIndex(ctx, articles, "entities_1_published_at_-1")
+		},
+	},
+}
+
+// batch size of rewriteArticles bulk writes
+const rewriteBatch = 1000
+
+// rewriteArticles sets the fields returned by fn on every article matching
+// filter; fn returns nil to leave an article unchanged.
+func rewriteArticles(ctx context.Context, coll *mongo.Collection, filter bson.M, fn func(models.Article) bson.M) error {
+	cur, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	writes := []mongo.WriteModel{}
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := coll.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+	for cur.Next(ctx) {
+		var doc struct {
+			ID             interface{} `bson:"_id"`
+			models.Article `bson:",inline"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return err
+		}
+		set := fn(doc.Article)
+		if set == nil {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": doc.ID}).SetUpdate(bson.M{"$set": set}))
+		if len(writes) >= rewriteBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func unsetArticleField(ctx context.Context, articles *mongo.Collection, field string) error {
+	_, err := articles.UpdateMany(ctx, bson.M{field: bson.M{"$exists": true}}, bson.M{"$unset": bson.M{field: ""}})
+	return err
+}
+
+// dropIndex drops an articles index, ignoring one that does not exist
+func dropIndex(ctx context.Context, articles *mongo.Collection, name string) error {
+	_, err := articles.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "IndexNotFound" {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// APIKey is a stored API credential. Only the SHA-256 hash of the key is kept;
+// Prefix holds the first characters so operators can tell keys apart.
+type APIKey struct {
+	ID        string    `bson:"id" json:"id"`
+	Name      string    `bson:"name" json:"name"`
+	Prefix    string    `bson:"prefix" json:"prefix"`
+	Hash      string    `bson:"hash" json:"-"`
+	Role      string    `bson:"role" json:"role"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	Revoked   bool      `bson:"revoked" json:"revoked"`
+}
@@ -2,16 +2,67 @@ package models
 
 import "time"
 
+// Article is a news item. RelevanceScore is the editorial score from the
+// source feed; ranking uses the computed score from services.RelevanceOf.
+// Lang is detected at ingest ("und" when unknown) and AutoCategory is set by
+// the classifier on articles without a topical category. Publication,
+// CanonicalURL and Location are derived from the source fields when stored.
+// IngestedAt is when the article was first stored and RewrittenAt when an
+// import last changed its title or description.
 type Article struct {
-	ID             string    `bson:"id" json:"id"`
-	Title          string    `bson:"title" json:"title"`
-	Description    string    `bson:"description" json:"description"`
-	URL            string    `bson:"url" json:"url"`
-	PublicationRaw string    `bson:"publication_date" json:"publication_date"`
-	Publication    time.Time `bson:"-" json:"-"`
-	SourceName     string    `bson:"source_name" json:"source_name"`
-	Category       []string  `bson:"category" json:"category"`
-	RelevanceScore float64   `bson:"relevance_score" json:"relevance_score"`
-	Latitude       float64   `bson:"latitude" json:"latitude"`
-	Longitude      float64   `bson:"longitude" json:"longitude"`
+	ID             string              `bson:"id" json:"id"`
+	Title          string              `bson:"title" json:"title"`
+	Description    string              `bson:"description" json:"description"`
+	URL            string              `bson:"url" json:"url"`
+	CanonicalURL   string              `bson:"canonical_url,omitempty" json:"-"`
+	PublicationRaw string              `bson:"publication_date" json:"publication_date"`
+	Publication    time.Time           `bson:"published_at,omitempty" json:"-"`
+	SourceName     string              `bson:"source_name" json:"source_name"`
+	Category       []string            `bson:"category" json:"category"`
+	RelevanceScore float64             `bson:"relevance_score" json:"relevance_score"`
+	Latitude       float64             `bson:"latitude" json:"latitude"`
+	Longitude      float64             `bson:"longitude" json:"longitude"`
+	Entities       []string            `bson:"entities" json:"entities,omitempty"`
+	Lang           string              `bson:"lang,omitempty" json:"lang"`
+	AutoCategory   *CategoryPrediction `bson:"auto_category,omitempty" json:"auto_category,omitempty"`
+	Location       *GeoPoint           `bson:"location,omitempty" json:"-"`
+	IngestedAt     time.Time           `bson:"ingested_at,omitempty" json:"-"`
+	RewrittenAt    time.Time           `bson:"rewritten_at,omitempty" json:"-"`
+}
+
+// GeoPoint is a GeoJSON point, stored alongside latitude/longitude for 2dsphere queries
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"` // longitude, latitude
+}
+
+// NewGeoPoint builds a point from latitude and longitude
+func NewGeoPoint(lat, lon float64) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{lon, lat}}
+}
+
+// CategoryPrediction is a machine-assigned category
+type CategoryPrediction struct {
+	Category   string  `bson:"category" json:"category"`
+	Confidence float64 `bson:"confidence" json:"confidence"`
+	Model      string  `bson:"model" json:"model"`
+}
+
+// publication date layouts seen in source feeds, most specific first
+var publicationLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParsePublication parses a raw publication date; dates without a zone are UTC.
+// It returns the zero time when the value cannot be parsed.
+func ParsePublication(raw string) time.Time {
+	for _, layout := range publicationLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
@@ -0,0 +1,9 @@
+package models
+
+// Entity is a person, organisation or place mentioned in articles. ID is a
+// normalized slug of the canonical name, e.g. "narendra-modi".
+type Entity struct {
+	ID   string `bson:"id" json:"id"`
+	Name string `bson:"name" json:"name"`
+	Type string `bson:"type" json:"type"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ReadEntry records that a user opened an article. Progress is the fraction
+// read (0-1) so apps can offer "continue reading".
+type ReadEntry struct {
+	UserID    string    `bson:"user_id" json:"-"`
+	ArticleID string    `bson:"article_id" json:"article_id"`
+	Progress  float64   `bson:"progress" json:"progress"`
+	ReadAt    time.Time `bson:"read_at" json:"read_at"`
+}
+
+// Bookmark is an article a user saved for later
+type Bookmark struct {
+	UserID    string    `bson:"user_id" json:"-"`
+	ArticleID string    `bson:"article_id" json:"article_id"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
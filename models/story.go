@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Story groups articles reporting on the same event. The representative is the
+// member closest to the story centroid and supplies the headline. Dirty marks
+// a story whose members were removed by another process; it is rebuilt when
+// loaded.
+type Story struct {
+	ID               string    `bson:"id" json:"id"`
+	Headline         string    `bson:"headline" json:"headline"`
+	RepresentativeID string    `bson:"representative_id" json:"representative_id"`
+	Members          []string  `bson:"members" json:"members"`
+	Sources          []string  `bson:"sources" json:"sources"`
+	SourceCount      int       `bson:"source_count" json:"source_count"`
+	Categories       []string  `bson:"categories" json:"categories"`
+	FirstSeen        time.Time `bson:"first_seen" json:"first_seen"`
+	LastSeen         time.Time `bson:"last_seen" json:"last_seen"`
+	Centroid         []float32 `bson:"centroid" json:"-"`
+	Dirty            bool      `bson:"dirty,omitempty" json:"-"`
+}
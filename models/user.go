@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// User holds the personalisation preferences of an authenticated caller.
+// ID is the principal subject (API key or JWT sub).
+type User struct {
+	ID                  string    `bson:"id" json:"id"`
+	PreferredCategories []string  `bson:"preferred_categories" json:"preferred_categories"`
+	FollowedSources     []string  `bson:"followed_sources" json:"followed_sources"`
+	MutedSources        []string  `bson:"muted_sources" json:"muted_sources"`
+	HomeLatitude        float64   `bson:"home_latitude" json:"home_latitude"`
+	HomeLongitude       float64   `bson:"home_longitude" json:"home_longitude"`
+	Language            string    `bson:"language" json:"language"`
+	CreatedAt           time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// HasHome reports whether a home location was set
+func (u User) HasHome() bool {
+	return u.HomeLatitude != 0 || u.HomeLongitude != 0
+}
@@ -0,0 +1,443 @@
+// Package openapi builds an OpenAPI 3 document from the gin route table and
+// per-route operation descriptions. Parameter and body schemas are derived
+// from the typed request structs (form, json and binding tags) and response
+// schemas from the response types, so the spec follows the code.
+package openapi
+
+import (
+	"encoding"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Operation describes one route. Query and Body are zero values of the
+// request structs, Response of the success body; any may be nil.
+type Operation struct {
+	Summary     string
+	Description string
+	Tag         string
+	// Role is the minimum role required; empty means the route is public
+	Role  string
+	Query interface{}
+	// Params are read outside the Query struct, such as headers
+	Params   []Parameter
+	Body     interface{}
+	Response interface{}
+	// Status is the success status, 200 when zero
+	Status int
+	// ContentType of the success body, application/json when empty
+	ContentType string
+}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*OpObject `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info is the document metadata
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the named schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is an API key or HTTP auth scheme
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// OpObject is an operation in the document
+type OpObject struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the generator emits
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+// Key identifies a route as "METHOD /path" with gin path syntax
+func Key(method, path string) string {
+	return method + " " + path
+}
+
+// Missing lists the registered routes that have no operation, sorted
+func Missing(routes gin.RoutesInfo, ops map[string]Operation) []string {
+	out := []string{}
+	for _, r := range routes {
+		if _, ok := ops[Key(r.Method, r.Path)]; !ok {
+			out = append(out, Key(r.Method, r.Path))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Build generates the document for the registered routes. Routes without an
+// operation are still listed, with only their path parameters.
+func Build(info Info, routes gin.RoutesInfo, ops map[string]Operation, errorBody interface{}) *Document {
+	g := &generator{schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*OpObject{},
+		Components: Components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"apiKey": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				"bearer": {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+	var problem *Schema
+	if errorBody != nil {
+		problem = g.named("Problem", reflect.TypeOf(errorBody))
+	}
+	for _, r := range routes {
+		op := ops[Key(r.Method, r.Path)]
+		path, params := convertPath(r.Path)
+		o := &OpObject{
+			Summary:     op.Summary,
+			Description: op.Description,
+			OperationID: operationID(r.Method, r.Path),
+			Parameters:  params,
+			Responses:   map[string]Response{},
+		}
+		if op.Tag != "" {
+			o.Tags = []string{op.Tag}
+		}
+		if op.Query != nil {
+			o.Parameters = append(o.Parameters, g.queryParams(reflect.TypeOf(op.Query))...)
+		}
+		o.Parameters = append(o.Parameters, op.Params...)
+		if op.Body != nil {
+			o.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/json": {Schema: g.schemaOf(reflect.TypeOf(op.Body))},
+			}}
+		}
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			ct := op.ContentType
+			if ct == "" {
+				ct = "application/json"
+			}
+			success.Content = map[string]MediaType{ct: {Schema: g.schemaOf(reflect.TypeOf(op.Response))}}
+		}
+		o.Responses[strconv.Itoa(status)] = success
+		if op.Role != "" {
+			o.Security = []map[string][]string{{"apiKey": {}}, {"bearer": {}}}
+			if o.Description != "" {
+				o.Description += "\n\n"
+			}
+			o.Description += "Requires the " + op.Role + " role."
+		}
+		if problem != nil {
+			codes := []int{http.StatusInternalServerError}
+			if op.Query != nil || op.Body != nil {
+				codes = append(codes, http.StatusBadRequest)
+			}
+			if op.Role != "" {
+				codes = append(codes, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests)
+			}
+			for _, code := range codes {
+				o.Responses[strconv.Itoa(code)] = Response{
+					Description: http.StatusText(code),
+					Content:     map[string]MediaType{"application/problem+json": {Schema: problem}},
+				}
+			}
+		}
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OpObject{}
+		}
+		doc.Paths[path][strings.ToLower(r.Method)] = o
+	}
+	return doc
+}
+
+// convertPath turns /articles/:id into /articles/{id} with its parameters
+func convertPath(p string) (string, []Parameter) {
+	params := []Parameter{}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			name := part[1:]
+			parts[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+// operationID is a stable identifier such as getApiV1NewsArticlesIdRelated
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ':' || r == '-' || r == '.' || r == '*' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaOf returns the schema of t; named structs become components
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.named(strings.ToUpper(t.Name()[:1])+t.Name()[1:], t)
+	}
+	return &Schema{}
+}
+
+// named registers struct t as a component and returns a reference to it
+func (g *generator) named(name string, t reflect.Type) *Schema {
+	if _, ok := g.schemas[name]; !ok {
+		// reserve the name first so recursive types terminate
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema describes a struct by its json fields, flattening embedded
+// structs the way encoding/json does. Fields of request structs (those with
+// binding tags) are required when their binding says so; response fields are
+// required unless they are omitempty or pointers.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	request := false
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			request = true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				inner := g.structSchema(et)
+				for k, v := range inner.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, inner.Required...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := g.schemaOf(f.Type)
+		required := applyBinding(fs, f.Tag.Get("binding"))
+		if !request {
+			required = !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// queryParams describes the form fields of a request struct
+func (g *generator) queryParams(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	out := []Parameter{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("form")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		s := g.schemaOf(f.Type)
+		for _, opt := range parts[1:] {
+			if v, ok := strings.CutPrefix(opt, "default="); ok {
+				s.Default = typedValue(s.Type, v)
+			}
+		}
+		required := applyBinding(s, f.Tag.Get("binding"))
+		out = append(out, Parameter{Name: parts[0], In: "query", Required: required, Schema: s})
+	}
+	return out
+}
+
+// applyBinding copies validator constraints onto s and reports whether the
+// field is required
+func applyBinding(s *Schema, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	numeric := s.Type == "integer" || s.Type == "number"
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "gte", "gt":
+			if numeric {
+				v, _ := strconv.ParseFloat(param, 64)
+				s.Minimum = &v
+				s.ExclusiveMinimum = name == "gt"
+			} else if n, err := strconv.Atoi(param); err == nil {
+				s.MinLength = &n
+			}
+		case "max", "lte":
+			if numeric {
+				v, _ := strconv.ParseFloat(param, 64)
+				s.Maximum = &v
+			} else if n, err := strconv.Atoi(param); err == nil {
+				s.MaxLength = &n
+			}
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, typedValue(s.Type, v))
+			}
+		}
+	}
+	return required
+}
+
+func typedValue(typ, v string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
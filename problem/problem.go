@@ -0,0 +1,215 @@
+// Package problem writes API errors as RFC 7807 problem details
+// (application/problem+json). Every error body carries a stable machine
+// readable code next to the HTTP status, and validation failures list the
+// offending fields.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"news-backend/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Error codes. Clients should branch on these rather than on detail text.
+const (
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeRateLimited      = "rate_limited"
+	CodeUnavailable      = "service_unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// Details is the problem document
+type Details struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// TypeURI identifies a problem type by its code
+func TypeURI(code string) string {
+	return "urn:news-backend:problem:" + code
+}
+
+// Abort writes a problem response and stops the handler chain
+func Abort(c *gin.Context, status int, code, detail string) {
+	write(c, Details{Status: status, Code: code, Detail: detail})
+}
+
+// Invalid rejects a request with field-level validation errors
+func Invalid(c *gin.Context, errs ...FieldError) {
+	write(c, Details{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidation,
+		Detail: "the request has invalid fields",
+		Errors: errs,
+	})
+}
+
+// Internal logs err and answers 500 without exposing it, since store and
+// provider errors can leak internals
+func Internal(c *gin.Context, err error) {
+	slog.ErrorContext(c.Request.Context(), "request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+	Abort(c, http.StatusInternalServerError, CodeInternal, "the request could not be completed")
+}
+
+// Unavailable answers 503 with a Retry-After in seconds
+func Unavailable(c *gin.Context, retryAfter int, detail string) {
+	c.Header("Retry-After", fmt.Sprint(retryAfter))
+	Abort(c, http.StatusServiceUnavailable, CodeUnavailable, detail)
+}
+
+func write(c *gin.Context, d Details) {
+	d.Type = TypeURI(d.Code)
+	d.Title = http.StatusText(d.Status)
+	d.Instance = c.Request.URL.Path
+	d.RequestID = logging.RequestID(c.Request.Context())
+	body, err := json.Marshal(d)
+	if err != nil {
+		body = []byte(`{"status":500,"code":"internal_error"}`)
+	}
+	c.Abort()
+	c.Data(d.Status, ContentType, body)
+}
+
+// BindError turns an error from gin binding into field errors. Validator
+// failures name their field; JSON type errors name theirs too. Anything else
+// (malformed JSON, an unparsable query value) is returned as a single error
+// on field, which may be empty when unknown.
+func BindError(err error) []FieldError {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		out := make([]FieldError, 0, len(ve))
+		for _, fe := range ve {
+			out = append(out, FieldError{Field: fieldPath(fe), Code: fe.Tag(), Message: message(fe)})
+		}
+		return out
+	}
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return []FieldError{{Field: te.Field, Code: "type", Message: "must be " + article(jsonKind(te.Type))}}
+	}
+	var se *json.SyntaxError
+	if errors.As(err, &se) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return []FieldError{{Field: "body", Code: "malformed", Message: "is not valid JSON"}}
+	}
+	return []FieldError{{Field: "", Code: "invalid", Message: err.Error()}}
+}
+
+// fieldPath is the request-facing name of the field, nested fields joined
+// with dots and without the top-level struct name
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		if isNumber(fe.Kind()) {
+			return "must be at least " + fe.Param()
+		}
+		return "must have at least " + fe.Param() + " characters or items"
+	case "max", "lte":
+		if isNumber(fe.Kind()) {
+			return "must be at most " + fe.Param()
+		}
+		return "must have at most " + fe.Param() + " characters or items"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "latitude":
+		return "must be a latitude between -90 and 90"
+	case "longitude":
+		return "must be a longitude between -180 and 180"
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func jsonKind(t reflect.Type) string {
+	switch {
+	case t == nil:
+		return "value"
+	case isNumber(t.Kind()):
+		return "number"
+	case t.Kind() == reflect.String:
+		return "string"
+	case t.Kind() == reflect.Bool:
+		return "boolean"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+func article(noun string) string {
+	if strings.ContainsRune("aeiou", rune(noun[0])) {
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func init() {
+	// report fields by the name clients send: the form or json tag
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"form", "json"} {
+				name := strings.Split(f.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
@@ -2,19 +2,68 @@ package routes
 
 import (
 	"news-backend/controllers"
+	"news-backend/middleware"
+	"news-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(router *gin.Engine) {
-	group := router.Group("/api/v1/news")
+	reader := router.Group("/api/v1/news", middleware.RateLimit(middleware.AuthPolicy), middleware.RequireRole(services.RoleReader))
 	{
+		// search scans every article, so it gets its own stricter budget
+		reader.GET("/search", middleware.RateLimit(middleware.SearchPolicy), controllers.SearchArticles)
+		reader.GET("/semantic-search", middleware.RateLimit(middleware.SearchPolicy), controllers.SemanticSearch)
+
+		group := reader.Group("", middleware.RateLimit(middleware.DefaultPolicy))
 		group.GET("/category", controllers.GetArticlesByCategory)
 		group.GET("/score", controllers.GetArticlesByScore)
-		group.GET("/search", controllers.SearchArticles)
 		group.GET("/source", controllers.GetArticlesBySource)
 		group.GET("/nearby", controllers.GetNearbyArticles)
 		group.GET("/trending", controllers.GetTrending)
-		group.GET("/process", controllers.ProcessQuery)
+		group.GET("/live", controllers.LiveFeed)
+		group.GET("/articles/:id/related", controllers.GetRelatedArticles)
+
+		// these have no read-only snapshot to fall back on
+		stored := group.Group("", middleware.RequireDatabase())
+		stored.GET("/feed", controllers.GetFeed)
+		stored.GET("/stories", controllers.ListStories)
+		stored.GET("/stories/:id", controllers.GetStory)
+		stored.GET("/entities", controllers.ListEntities)
+		stored.GET("/entities/:id", controllers.GetEntity)
+		stored.GET("/entities/:id/articles", controllers.GetEntityArticles)
+	}
+
+	// GraphQL can resolve several lookups that each scan every article, so it
+	// gets the search budget
+	graph := router.Group("/graphql", middleware.RateLimit(middleware.AuthPolicy), middleware.RequireRole(services.RoleReader), middleware.RateLimit(middleware.SearchPolicy))
+	{
+		graph.GET("", controllers.GraphQL)
+		graph.POST("", controllers.GraphQL)
+	}
+
+	users := router.Group("/api/v1/users", middleware.RateLimit(middleware.AuthPolicy), middleware.RequireRole(services.RoleReader), middleware.RateLimit(middleware.DefaultPolicy), middleware.RequireDatabase())
+	{
+		users.GET("/me", controllers.GetMyPreferences)
+		users.PUT("/me", controllers.UpdateMyPreferences)
+		users.GET("/me/history", controllers.ListReadingHistory)
+		users.POST("/me/history", controllers.RecordRead)
+		users.GET("/me/bookmarks", controllers.ListBookmarks)
+		users.POST("/me/bookmarks", controllers.AddBookmark)
+		users.DELETE("/me/bookmarks/:article_id", controllers.RemoveBookmark)
+	}
+
+	// query processing will call a paid LLM, so it is limited to editors
+	editor := router.Group("/api/v1/news", middleware.RateLimit(middleware.AuthPolicy), middleware.RequireRole(services.RoleEditor))
+	{
+		editor.GET("/process", middleware.RateLimit(middleware.ProcessPolicy), controllers.ProcessQuery)
+	}
+
+	admin := router.Group("/api/v1/admin", middleware.RateLimit(middleware.AuthPolicy), middleware.RequireRole(services.RoleAdmin), middleware.RateLimit(middleware.DefaultPolicy))
+	{
+		admin.GET("/keys", middleware.RequireDatabase(), controllers.ListAPIKeys)
+		admin.POST("/keys", middleware.RequireDatabase(), controllers.CreateAPIKey)
+		admin.DELETE("/keys/:id", middleware.RequireDatabase(), controllers.RevokeAPIKey)
+		admin.GET("/config", controllers.GetConfig)
 	}
 }
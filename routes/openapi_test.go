@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"testing"
+
+	"news-backend/controllers"
+	"news-backend/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// every registered route needs an entry in controllers.Operations
+func TestEveryRouteDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewRouter()
+	if missing := openapi.Missing(router.Routes(), controllers.Operations); len(missing) > 0 {
+		t.Fatalf("routes without an OpenAPI operation in controllers.Operations: %v", missing)
+	}
+}
@@ -0,0 +1,53 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+
+	"news-backend/controllers"
+	"news-backend/metrics"
+	"news-backend/middleware"
+	"news-backend/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter builds the engine with its middleware and every route
+func NewRouter() *gin.Engine {
+	router := gin.New()
+	gin.DebugPrintRouteFunc = func(method, path, handler string, _ int) {
+		slog.Debug("route", "method", method, "path", path, "handler", handler)
+	}
+
+	// Middleware
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.AccessLog())
+	// metrics wrap recovery so recovered panics are counted as 500s
+	router.Use(middleware.Metrics())
+	router.Use(middleware.Recovery())
+	router.Use(middleware.ReadOnlyWhenDegraded())
+
+	// Health check endpoint
+	router.GET("/health", controllers.HealthCheck)
+	router.GET("/livez", controllers.Livez)
+	router.GET("/readyz", controllers.Readyz)
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// API documentation, generated from the routes registered here
+	router.GET("/openapi.json", controllers.OpenAPISpec(router))
+	router.GET("/docs", controllers.APIDocs)
+
+	// API routes
+	SetupRoutes(router)
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "no route for "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		problem.Abort(c, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+	})
+	return router
+}
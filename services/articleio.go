@@ -0,0 +1,358 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"news-backend/models"
+)
+
+// Article file formats
+const (
+	FormatJSON  = "json"  // a JSON array of articles, as in data/news_data.json
+	FormatJSONL = "jsonl" // one JSON article per line
+	FormatCSV   = "csv"   // a header row then one article per row
+)
+
+// FormatFromPath guesses the format from a file extension
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("cannot tell format of %q, pass it explicitly", path)
+}
+
+// ArticleReader decodes articles one at a time. Next returns io.EOF after the
+// last record; a *RecordError means that record was unreadable but later
+// records can still be read.
+type ArticleReader interface {
+	Next() (models.Article, error)
+}
+
+// RecordError describes a rejected input record
+type RecordError struct {
+	Record int      `json:"record"` // 1-based position in the input
+	ID     string   `json:"id,omitempty"`
+	URL    string   `json:"url,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %s", e.Record, strings.Join(e.Errors, "; "))
+}
+
+// NewArticleReader returns a streaming reader for the given format
+func NewArticleReader(r io.Reader, format string) (ArticleReader, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonArrayReader{dec: json.NewDecoder(bufio.NewReader(r))}, nil
+	case FormatJSONL:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		return &jsonlReader{sc: sc}, nil
+	case FormatCSV:
+		cr := csv.NewReader(bufio.NewReader(r))
+		cr.FieldsPerRecord = -1
+		return &csvReader{r: cr}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+type jsonArrayReader struct {
+	dec     *json.Decoder
+	n       int
+	started bool
+}
+
+func (j *jsonArrayReader) Next() (models.Article, error) {
+	if !j.started {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return models.Article{}, err
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return models.Article{}, errors.New("json input must be an array of articles")
+		}
+		j.started = true
+	}
+	if !j.dec.More() {
+		return models.Article{}, io.EOF
+	}
+	j.n++
+	// decode into raw first so a record of the wrong shape does not stop the stream
+	var raw json.RawMessage
+	if err := j.dec.Decode(&raw); err != nil {
+		return models.Article{}, err
+	}
+	var a models.Article
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return models.Article{}, &RecordError{Record: j.n, Errors: []string{err.Error()}}
+	}
+	return a, nil
+}
+
+type jsonlReader struct {
+	sc *bufio.Scanner
+	n  int
+}
+
+func (j *jsonlReader) Next() (models.Article, error) {
+	for j.sc.Scan() {
+		line := strings.TrimSpace(j.sc.Text())
+		if line == "" {
+			continue
+		}
+		j.n++
+		var a models.Article
+		if err := json.Unmarshal([]byte(line), &a); err != nil {
+			return models.Article{}, &RecordError{Record: j.n, Errors: []string{err.Error()}}
+		}
+		return a, nil
+	}
+	if err := j.sc.Err(); err != nil {
+		return models.Article{}, err
+	}
+	return models.Article{}, io.EOF
+}
+
+// CSVColumns is the column order used for CSV export; imports match columns by header name
+var CSVColumns = []string{
+	"id", "title", "description", "url", "publication_date", "source_name",
+	"category", "relevance_score", "latitude", "longitude", "lang",
+}
+
+type csvReader struct {
+	r      *csv.Reader
+	header map[string]int
+	n      int
+}
+
+func (c *csvReader) Next() (models.Article, error) {
+	if c.header == nil {
+		row, err := c.r.Read()
+		if err != nil {
+			return models.Article{}, err
+		}
+		c.header = map[string]int{}
+		for i, h := range row {
+			c.header[strings.ToLower(strings.TrimSpace(h))] = i
+		}
+		if _, ok := c.header["title"]; !ok {
+			return models.Article{}, errors.New("csv header must include a title column")
+		}
+	}
+	row, err := c.r.Read()
+	if err != nil {
+		return models.Article{}, err
+	}
+	c.n++
+	get := func(col string) string {
+		if i, ok := c.header[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	a := models.Article{
+		ID:             get("id"),
+		Title:          get("title"),
+		Description:    get("description"),
+		URL:            get("url"),
+		PublicationRaw: get("publication_date"),
+		SourceName:     get("source_name"),
+		Lang:           get("lang"),
+	}
+	if cats := get("category"); cats != "" {
+		for _, cat := range strings.FieldsFunc(cats, func(r rune) bool { return r == ';' || r == '|' }) {
+			if cat = strings.TrimSpace(cat); cat != "" {
+				a.Category = append(a.Category, cat)
+			}
+		}
+	}
+	problems := []string{}
+	for col, dst := range map[string]*float64{"relevance_score": &a.RelevanceScore, "latitude": &a.Latitude, "longitude": &a.Longitude} {
+		if v := get(col); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				problems = append(problems, col+" is not a number")
+				continue
+			}
+			*dst = f
+		}
+	}
+	if len(problems) > 0 {
+		return models.Article{}, &RecordError{Record: c.n, ID: a.ID, URL: a.URL, Errors: problems}
+	}
+	return a, nil
+}
+
+// ValidateArticle returns the problems that make an article unfit to store
+func ValidateArticle(a models.Article) []string {
+	problems := []string{}
+	if strings.TrimSpace(a.ID) == "" && strings.TrimSpace(a.URL) == "" {
+		problems = append(problems, "id or url required")
+	}
+	if strings.TrimSpace(a.Title) == "" {
+		problems = append(problems, "title required")
+	}
+	if a.URL != "" {
+		if u, err := url.Parse(a.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "url must be an absolute http(s) URL")
+		}
+	}
+	if a.PublicationRaw != "" && models.ParsePublication(a.PublicationRaw).IsZero() {
+		problems = append(problems, "publication_date is not a recognised date")
+	}
+	if math.IsNaN(a.RelevanceScore) || a.RelevanceScore < 0 || a.RelevanceScore > 1 {
+		problems = append(problems, "relevance_score must be between 0 and 1")
+	}
+	if a.Latitude < -90 || a.Latitude > 90 || a.Longitude < -180 || a.Longitude > 180 {
+		problems = append(problems, "latitude/longitude out of range")
+	}
+	return problems
+}
+
+// tracking parameters dropped from canonical URLs
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "ref": true, "ref_src": true}
+
+// CanonicalURL normalises an article URL so that the same page from different
+// links compares equal: lowercase scheme and host without "www.", no
+// fragment, tracking parameters or trailing slash. It returns "" for
+// unparsable URLs.
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// ArticleWriter encodes articles in one of the file formats
+type ArticleWriter interface {
+	Write(a models.Article) error
+	// Close finishes the output (closing the JSON array, flushing CSV); it
+	// does not close the underlying writer
+	Close() error
+}
+
+// NewArticleWriter returns a writer for the given format
+func NewArticleWriter(w io.Writer, format string) (ArticleWriter, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatJSON:
+		return &jsonArrayWriter{w: bw}, nil
+	case FormatJSONL:
+		return &jsonlWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(bw), bw: bw}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+type jsonArrayWriter struct {
+	w *bufio.Writer
+	n int
+}
+
+func (j *jsonArrayWriter) Write(a models.Article) error {
+	b, err := json.MarshalIndent(a, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if j.n == 0 {
+		sep = "[\n  "
+	}
+	j.n++
+	if _, err := j.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonArrayWriter) Close() error {
+	end := "\n]\n"
+	if j.n == 0 {
+		end = "[]\n"
+	}
+	if _, err := j.w.WriteString(end); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(a models.Article) error {
+	return j.enc.Encode(a)
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	bw     *bufio.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(a models.Article) error {
+	if !c.header {
+		if err := c.w.Write(CSVColumns); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return c.w.Write([]string{
+		a.ID, a.Title, a.Description, a.URL, a.PublicationRaw, a.SourceName,
+		strings.Join(a.Category, ";"), f(a.RelevanceScore), f(a.Latitude), f(a.Longitude), a.Lang,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		if err := c.w.Write(CSVColumns); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return err
+	}
+	return c.bw.Flush()
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Keys an import can match existing articles on
+const (
+	MatchByID  = "id"
+	MatchByURL = "url"
+)
+
+var articlesColl *mongo.Collection
+
+// InitArticles wires the articles collection and its indexes.
+func InitArticles(client *mongo.Client, dbName, collName string) {
+	articlesColl = client.Database(dbName).Collection(collName)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := articlesColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "canonical_url", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "entities", Value: 1}, {Key: "published_at", Value: -1}}},
+		{Keys: bson.D{{Key: "ingested_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "rewritten_at", Value: 1}}, Options: options.Index().SetSparse(true)},
+	}); err != nil {
+		slog.ErrorContext(ctx, "articles indexes", "error", err)
+	}
+}
+
+// NewestPublication returns the publication time of the most recent stored
+// article, or the zero time when there is none
+func NewestPublication(ctx context.Context) (time.Time, error) {
+	if articlesColl == nil {
+		return time.Time{}, errors.New("articles collection not initialised")
+	}
+	var a models.Article
+	err := articlesColl.FindOne(ctx, bson.M{"published_at": bson.M{"$exists": true}},
+		options.FindOne().SetSort(bson.D{{Key: "published_at", Value: -1}}).SetProjection(bson.M{"published_at": 1})).Decode(&a)
+	if err == mongo.ErrNoDocuments {
+		return time.Time{}, nil
+	}
+	return a.Publication, err
+}
+
+// LoadAllArticles reads every stored article with its publication time parsed
+func LoadAllArticles(ctx context.Context) ([]models.Article, error) {
+	return findArticles(ctx, bson.M{})
+}
+
+// FindArticlesByIDs reads the stored articles with the given IDs
+func FindArticlesByIDs(ctx context.Context, ids []string) ([]models.Article, error) {
+	return findArticles(ctx, bson.M{"id": bson.M{"$in": ids}})
+}
+
+// RecentArticles reads the n most recently published articles
+func RecentArticles(ctx context.Context, n int) ([]models.Article, error) {
+	return findArticles(ctx, bson.M{},
+		options.Find().SetSort(bson.D{{Key: "published_at", Value: -1}}).SetLimit(int64(n)))
+}
+
+// findArticles decodes the matching articles, skipping documents that fail
+// to decode
+func findArticles(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]models.Article, error) {
+	if articlesColl == nil {
+		return nil, ErrDatabaseUnavailable
+	}
+	cur, err := articlesColl.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := []models.Article{}
+	for cur.Next(ctx) {
+		var a models.Article
+		if err := cur.Decode(&a); err != nil {
+			continue
+		}
+		a.Publication = models.ParsePublication(a.PublicationRaw)
+		out = append(out, a)
+	}
+	return out, cur.Err()
+}
+
+// ImportResult counts what an upsert did
+type ImportResult struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Matched  int64 `json:"matched"`
+	// InsertedIDs are the article IDs that did not exist before
+	InsertedIDs []string `json:"-"`
+}
+
+// Add accumulates another batch's counts
+func (r *ImportResult) Add(o ImportResult) {
+	r.Inserted += o.Inserted
+	r.Updated += o.Updated
+	r.Matched += o.Matched
+	r.InsertedIDs = append(r.InsertedIDs, o.InsertedIDs...)
+}
+
+// PrepareForImport fills the fields derived from the source ones: the
+// canonical URL, an ID derived from it when the record has none, and the
+// detected language.
+func PrepareForImport(a *models.Article) {
+	if a.CanonicalURL == "" {
+		a.CanonicalURL = CanonicalURL(a.URL)
+	}
+	if a.ID == "" && a.CanonicalURL != "" {
+		a.ID = idFromURL(a.CanonicalURL)
+	}
+	if a.Lang == "" {
+		a.Lang = ArticleLanguage(*a)
+	}
+}
+
+// idFromURL derives a stable UUID-shaped ID from a canonical URL
+func idFromURL(u string) string {
+	sum := sha1.Sum([]byte(u))
+	h := hex.EncodeToString(sum[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// UpsertArticles writes prepared articles, matching existing documents by ID
+// or by canonical URL. Articles without entities are tagged here, on every
+// ingest path, and the entities saved to the catalog. Source fields and
+// entities are overwritten, the auto category only when the record carries
+// it, and an article matched by URL keeps its stored ID. Inserted articles are queued for the ingest
+// pipeline (QueueIndex), which fills in anything missing; when a stored title
+// or description changes, the entities, auto category, embedding and story
+// membership derived from it are dropped and the article is queued as well.
+// Inserted articles are published to live listeners in this process; other
+// processes pick them up by their ingested_at and rewritten_at stamps (see
+// WatchImports).
+func UpsertArticles(ctx context.Context, articles []models.Article, matchBy string) (ImportResult, error) {
+	if len(articles) == 0 {
+		return ImportResult{}, nil
+	}
+	rewritten, err := rewrittenArticles(ctx, articles, matchBy)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	catalog := TagEntities(articles)
+	// stored times have millisecond precision; rewrites are matched by them
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	writes := make([]mongo.WriteModel, 0, len(articles))
+	for i, a := range articles {
+		set := bson.M{
+			"title":            a.Title,
+			"description":      a.Description,
+			"url":              a.URL,
+			"canonical_url":    a.CanonicalURL,
+			"publication_date": a.PublicationRaw,
+			"source_name":      a.SourceName,
+			"category":         nonNil(a.Category),
+			"relevance_score":  a.RelevanceScore,
+			"latitude":         a.Latitude,
+			"longitude":        a.Longitude,
+			"lang":             a.Lang,
+			"location":         models.NewGeoPoint(a.Latitude, a.Longitude),
+		}
+		if t := models.ParsePublication(a.PublicationRaw); !t.IsZero() {
+			set["published_at"] = t
+		}
+		if a.Entities != nil {
+			set["entities"] = a.Entities
+		}
+		if a.AutoCategory != nil {
+			set["auto_category"] = a.AutoCategory
+		}
+		// matching by ID seeds the ID of inserted documents from the filter
+		filter := bson.M{"id": a.ID}
+		onInsert := bson.M{"ingested_at": now}
+		if matchBy == MatchByURL {
+			filter = bson.M{"$or": bson.A{bson.M{"canonical_url": a.CanonicalURL}, bson.M{"url": a.URL}}}
+			onInsert["id"] = a.ID
+		}
+		update := bson.M{"$set": set, "$setOnInsert": onInsert}
+		if _, ok := rewritten[i]; ok {
+			set["rewritten_at"] = now
+			unset := bson.M{}
+			for _, f := range []string{"entities", "auto_category"} {
+				if _, ok := set[f]; !ok {
+					unset[f] = ""
+				}
+			}
+			if len(unset) > 0 {
+				update["$unset"] = unset
+			}
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+	res, err := articlesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if res == nil {
+		return ImportResult{}, err
+	}
+	out := ImportResult{Inserted: res.UpsertedCount, Updated: res.ModifiedCount, Matched: res.MatchedCount}
+	fresh := make([]models.Article, 0, len(res.UpsertedIDs))
+	for idx := range res.UpsertedIDs {
+		out.InsertedIDs = append(out.InsertedIDs, articles[idx].ID)
+		a := articles[int(idx)]
+		a.IngestedAt = now
+		fresh = append(fresh, a)
+	}
+	if cerr := SaveEntityCatalog(ctx, catalog); cerr != nil {
+		slog.ErrorContext(ctx, "save entity catalog", "error", cerr)
+	}
+	PublishArticles(fresh)
+	QueueIndex(out.InsertedIDs)
+	if len(rewritten) > 0 {
+		ids := make([]string, 0, len(rewritten))
+		for _, id := range rewritten {
+			ids = append(ids, id)
+			handledRewrites.add(rewriteKey(id, now))
+		}
+		if ferr := forgetDerived(ctx, ids); ferr != nil {
+			slog.ErrorContext(ctx, "drop embeddings and stories of rewritten articles", "error", ferr)
+		}
+		QueueIndex(ids)
+	}
+	return out, err
+}
+
+// rewrittenArticles returns, by position in articles, the stored ID of each
+// article whose stored title or description differs from the record's
+func rewrittenArticles(ctx context.Context, articles []models.Article, matchBy string) (map[int]string, error) {
+	keys := []string{}
+	for _, a := range articles {
+		if matchBy == MatchByURL {
+			keys = append(keys, a.CanonicalURL, a.URL)
+		} else {
+			keys = append(keys, a.ID)
+		}
+	}
+	filter := bson.M{"id": bson.M{"$in": keys}}
+	if matchBy == MatchByURL {
+		filter = bson.M{"$or": bson.A{bson.M{"canonical_url": bson.M{"$in": keys}}, bson.M{"url": bson.M{"$in": keys}}}}
+	}
+	stored, err := findArticles(ctx, filter, options.Find().SetProjection(bson.M{
+		"id": 1, "title": 1, "description": 1, "url": 1, "canonical_url": 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	byID, byCanonical, byURL := map[string]models.Article{}, map[string]models.Article{}, map[string]models.Article{}
+	for _, s := range stored {
+		byID[s.ID] = s
+		if s.CanonicalURL != "" {
+			byCanonical[s.CanonicalURL] = s
+		}
+		byURL[s.URL] = s
+	}
+	out := map[int]string{}
+	for i, a := range articles {
+		s, ok := byID[a.ID]
+		if matchBy == MatchByURL {
+			if s, ok = byCanonical[a.CanonicalURL]; !ok {
+				s, ok = byURL[a.URL]
+			}
+		}
+		if ok && (s.Title != a.Title || s.Description != a.Description) {
+			out[i] = s.ID
+		}
+	}
+	return out, nil
+}
+
+// forgetDerived drops the stored embeddings and story membership of
+// articles, in the database and in this process
+func forgetDerived(ctx context.Context, ids []string) error {
+	forgetSummaries(ids)
+	database := articlesColl.Database()
+	if err := forgetEmbeddings(ctx, database.Collection(embeddingsCollection), ids); err != nil {
+		return err
+	}
+	return leaveStories(ctx, database.Collection(storiesCollection), ids)
+}
+
+// handledRewrites holds the rewrites this process already dealt with, so
+// WatchImports does not forget the recomputed results again
+var handledRewrites = &recentKeys{window: 2 * importWatchLookback, at: map[string]time.Time{}}
+
+func rewriteKey(id string, at time.Time) string {
+	return id + "@" + strconv.FormatInt(at.UnixMilli(), 10)
+}
+
+const (
+	// importWatchLookback re-reads this far behind the newest stamp seen, so
+	// articles stamped by a writer whose clock lags ours are not missed
+	importWatchLookback = time.Minute
+	// only the newest of a large import are pushed; live listeners could not
+	// keep up with more anyway
+	importWatchLimit = 500
+)
+
+// WatchImports follows articles written by other processes, such as
+// "newsctl import", polling every interval until ctx is done. Inserted
+// articles are published to live listeners; rewritten ones lose the
+// embedding and story membership this process holds for their old text. Both
+// are queued for indexing.
+func WatchImports(ctx context.Context, interval time.Duration) {
+	inserted := newestStamp(ctx, "ingested_at", func(a models.Article) time.Time { return a.IngestedAt })
+	rewritten := newestStamp(ctx, "rewritten_at", func(a models.Article) time.Time { return a.RewrittenAt })
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if !DatabaseAvailable() {
+			continue
+		}
+		qctx, cancel := context.WithTimeout(ctx, interval)
+		fresh, err := inserted.poll(qctx)
+		if err == nil {
+			PublishArticles(fresh)
+			ids := make([]string, len(fresh))
+			for i, a := range fresh {
+				ids[i] = a.ID
+			}
+			QueueIndex(ids)
+			var changed []models.Article
+			if changed, err = rewritten.poll(qctx); err == nil {
+				ids := []string{}
+				for _, a := range changed {
+					if handledRewrites.add(rewriteKey(a.ID, a.RewrittenAt)) {
+						ids = append(ids, a.ID)
+					}
+				}
+				if len(ids) > 0 {
+					err = forgetDerived(qctx, ids)
+					QueueIndex(ids)
+				}
+			}
+		}
+		cancel()
+		if err != nil {
+			slog.WarnContext(ctx, "watch imports", "error", err)
+		}
+	}
+}
+
+// stampWatch polls for articles whose time stamp field moved past the newest
+// one seen. seen holds what the lookback window would return again.
+type stampWatch struct {
+	field string
+	stamp func(models.Article) time.Time
+	since time.Time
+	seen  map[string]time.Time
+}
+
+func newestStamp(ctx context.Context, field string, stamp func(models.Article) time.Time) *stampWatch {
+	w := &stampWatch{field: field, stamp: stamp, seen: map[string]time.Time{}}
+	var a models.Article
+	err := articlesColl.FindOne(ctx, bson.M{field: bson.M{"$exists": true}},
+		options.FindOne().SetSort(bson.D{{Key: field, Value: -1}}).SetProjection(bson.M{field: 1})).Decode(&a)
+	if err != nil && err != mongo.ErrNoDocuments {
+		slog.ErrorContext(ctx, "watch imports", "field", field, "error", err)
+	}
+	w.since = stamp(a)
+	// what was stamped before we started is not news
+	recent, err := findArticles(ctx, bson.M{field: bson.M{"$gt": w.since.Add(-importWatchLookback)}},
+		options.Find().SetProjection(bson.M{"id": 1, field: 1}))
+	if err != nil {
+		slog.ErrorContext(ctx, "watch imports", "field", field, "error", err)
+	}
+	for _, a := range recent {
+		w.seen[a.ID] = stamp(a)
+	}
+	return w
+}
+
+// poll returns the newest articles stamped since the last poll, oldest first
+func (w *stampWatch) poll(ctx context.Context) ([]models.Article, error) {
+	found, err := findArticles(ctx, bson.M{w.field: bson.M{"$gt": w.since.Add(-importWatchLookback)}},
+		options.Find().SetSort(bson.D{{Key: w.field, Value: -1}}).SetLimit(importWatchLimit))
+	if err != nil {
+		return nil, err
+	}
+	if len(found) > 0 && w.stamp(found[0]).After(w.since) {
+		w.since = w.stamp(found[0])
+	}
+	for id, at := range w.seen {
+		if !at.After(w.since.Add(-importWatchLookback)) {
+			delete(w.seen, id)
+		}
+	}
+	out := []models.Article{}
+	for _, a := range slices.Backward(found) {
+		if at, ok := w.seen[a.ID]; ok && at.Equal(w.stamp(a)) {
+			continue
+		}
+		w.seen[a.ID] = w.stamp(a)
+		out = append(out, a)
+	}
+	return out, nil
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Roles, ordered from least to most privileged
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{RoleReader: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// ValidRole reports whether r is a known role
+func ValidRole(r string) bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// RoleAllows reports whether a principal holding role have may access a route requiring need
+func RoleAllows(have, need string) bool {
+	return roleRank[have] >= roleRank[need] && roleRank[have] > 0
+}
+
+// Principal is the authenticated caller attached to a request
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Method  string `json:"method"` // api_key or jwt
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrKeyNotFound        = errors.New("api key not found")
+
+	apiKeysColl *mongo.Collection
+
+	keyCacheMu sync.RWMutex
+	keyCache   = map[string]cachedKey{}
+)
+
+type cachedKey struct {
+	At  time.Time
+	Key *models.APIKey
+}
+
+// InitAuth wires the API key store to the given database and ensures its indexes.
+func InitAuth(client *mongo.Client, dbName string) {
+	apiKeysColl = client.Database(dbName).Collection("api_keys")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := apiKeysColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "api key indexes", "error", err)
+	}
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateAPIKey generates a new key with the given role. The plaintext key is
+// returned once and never stored.
+func CreateAPIKey(ctx context.Context, name, role string) (string, models.APIKey, error) {
+	if !ValidRole(role) {
+		return "", models.APIKey{}, fmt.Errorf("unknown role %q", role)
+	}
+	if apiKeysColl == nil {
+		return "", models.APIKey{}, ErrDatabaseUnavailable
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	plain := "nk_" + secret
+	key := models.APIKey{
+		ID:        id,
+		Name:      name,
+		Prefix:    plain[:10],
+		Hash:      hashKey(plain),
+		Role:      role,
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := apiKeysColl.InsertOne(ctx, key); err != nil {
+		return "", models.APIKey{}, err
+	}
+	return plain, key, nil
+}
+
+// ListAPIKeys returns all stored keys (without hashes in their JSON form)
+func ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	if apiKeysColl == nil {
+		return nil, ErrDatabaseUnavailable
+	}
+	cur, err := apiKeysColl.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := []models.APIKey{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeAPIKey marks a key as revoked so it can no longer authenticate
+func RevokeAPIKey(ctx context.Context, id string) error {
+	if apiKeysColl == nil {
+		return ErrDatabaseUnavailable
+	}
+	res, err := apiKeysColl.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrKeyNotFound
+	}
+	// drop cached lookups so revocation takes effect immediately on this instance
+	keyCacheMu.Lock()
+	keyCache = map[string]cachedKey{}
+	keyCacheMu.Unlock()
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext key to a principal
+func AuthenticateAPIKey(ctx context.Context, plain string) (Principal, error) {
+	// bootstrap admin key from the environment, used to create the first stored keys
+	if boot := config.Get().Auth.BootstrapAdminKey; boot != "" &&
+		subtle.ConstantTimeCompare([]byte(boot), []byte(plain)) == 1 {
+		return Principal{Subject: "bootstrap", Role: RoleAdmin, Method: "api_key"}, nil
+	}
+	h := hashKey(plain)
+	keyCacheMu.RLock()
+	e, ok := keyCache[h]
+	keyCacheMu.RUnlock()
+	if !ok || time.Since(e.At) > config.Get().Cache.APIKeyTTL.D() {
+		if apiKeysColl == nil {
+			return Principal{}, ErrDatabaseUnavailable
+		}
+		var k models.APIKey
+		err := apiKeysColl.FindOne(ctx, bson.M{"hash": h}).Decode(&k)
+		if err == mongo.ErrNoDocuments {
+			// misses are not cached, or every guessed key would grow the cache
+			return Principal{}, ErrInvalidCredentials
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		e = cachedKey{At: time.Now(), Key: &k}
+		keyCacheMu.Lock()
+		keyCache[h] = e
+		keyCacheMu.Unlock()
+	}
+	if e.Key == nil || e.Key.Revoked {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Subject: "key:" + e.Key.ID, Role: e.Key.Role, Method: "api_key"}, nil
+}
+
+// jwtClaims holds the registered and custom claims we understand
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Role      string          `json:"role"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt int64           `json:"exp"`
+	NotBefore int64           `json:"nbf"`
+}
+
+// VerifyJWT validates an HS256 or RS256 token against the keys configured in
+// JWT_HS256_SECRET / JWT_RS256_PUBLIC_KEY and returns its principal.
+func VerifyJWT(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidCredentials
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+
+	switch header.Alg {
+	case "HS256":
+		secret := config.Get().Auth.JWTHS256Secret
+		if secret == "" {
+			return Principal{}, errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return Principal{}, ErrInvalidCredentials
+		}
+	case "RS256":
+		pub, err := rsaPublicKey()
+		if err != nil {
+			return Principal{}, err
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+			return Principal{}, ErrInvalidCredentials
+		}
+	default:
+		return Principal{}, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	now := time.Now().Unix()
+	if claims.ExpiresAt == 0 || now >= claims.ExpiresAt {
+		return Principal{}, errors.New("token expired")
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return Principal{}, errors.New("token not yet valid")
+	}
+	if iss := config.Get().Auth.JWTIssuer; iss != "" && claims.Issuer != iss {
+		return Principal{}, errors.New("unexpected token issuer")
+	}
+	if aud := config.Get().Auth.JWTAudience; aud != "" && !audienceContains(claims.Audience, aud) {
+		return Principal{}, errors.New("unexpected token audience")
+	}
+	role := claims.Role
+	if role == "" {
+		role = RoleReader
+	}
+	if !ValidRole(role) {
+		return Principal{}, fmt.Errorf("unknown role %q", role)
+	}
+	return Principal{Subject: claims.Subject, Role: role, Method: "jwt"}, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// aud may be a single string or an array of strings
+func audienceContains(raw json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	rsaKeyOnce sync.Once
+	rsaKey     *rsa.PublicKey
+	rsaKeyErr  error
+)
+
+// rsaPublicKey parses auth.jwt_rs256_public_key (PEM, PKIX or PKCS#1) once
+func rsaPublicKey() (*rsa.PublicKey, error) {
+	rsaKeyOnce.Do(func() {
+		raw := config.Get().Auth.JWTRS256PublicKey
+		if raw == "" {
+			rsaKeyErr = errors.New("RS256 tokens are not accepted")
+			return
+		}
+		block, _ := pem.Decode([]byte(raw))
+		if block == nil {
+			rsaKeyErr = errors.New("invalid JWT_RS256_PUBLIC_KEY")
+			return
+		}
+		if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+			rsaKey = k
+			return
+		}
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			rsaKeyErr = err
+			return
+		}
+		pk, ok := k.(*rsa.PublicKey)
+		if !ok {
+			rsaKeyErr = errors.New("JWT_RS256_PUBLIC_KEY is not an RSA key")
+			return
+		}
+		rsaKey = pk
+	})
+	return rsaKey, rsaKeyErr
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"news-backend/config"
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CanonicalCategories in priority order: when an article carries several, the
+// most specific one (earliest here) is its primary category.
+var CanonicalCategories = []string{
+	"sports", "technology", "business", "entertainment", "health", "science", "education",
+	"crime", "lifestyle", "automobile", "defence", "politics", "world", "national",
+}
+
+// raw source labels -> canonical category; labels missing here carry no topic
+var categoryAliases = map[string]string{
+	"sports": "sports", "ipl_2025": "sports", "ipl": "sports", "cricket": "sports", "football": "sports",
+	"technology": "technology", "tech": "technology",
+	"business": "business", "finance": "business", "startup": "business", "economy": "business",
+	"entertainment": "entertainment", "bollywood": "entertainment",
+	"health": "health", "health___fitness": "health",
+	"science":   "science",
+	"education": "education",
+	"crime":     "crime",
+	"lifestyle": "lifestyle", "travel": "lifestyle", "hatke": "lifestyle", "feel_good_stories": "lifestyle",
+	"automobile": "automobile",
+	"defence":    "defence", "defense": "defence",
+	"politics": "politics",
+	"world":    "world", "russia-ukraine_conflict": "world", "israel-hamas_war": "world",
+	"national": "national", "city": "national",
+}
+
+// CanonicalCategory maps a source label to its canonical category
+func CanonicalCategory(raw string) (string, bool) {
+	c, ok := categoryAliases[strings.ToLower(strings.TrimSpace(raw))]
+	return c, ok
+}
+
+// PrimaryCategory returns the most specific canonical category of the labels,
+// or "" when none of them carries a topic (e.g. "General", "miscellaneous").
+func PrimaryCategory(labels []string) string {
+	have := map[string]bool{}
+	for _, l := range labels {
+		if c, ok := CanonicalCategory(l); ok {
+			have[c] = true
+		}
+	}
+	for _, c := range CanonicalCategories {
+		if have[c] {
+			return c
+		}
+	}
+	return ""
+}
+
+// NeedsCategory reports whether an article has no topical category
+func NeedsCategory(a models.Article) bool {
+	return PrimaryCategory(a.Category) == ""
+}
+
+// NaiveBayes is a multinomial naive Bayes text classifier with Laplace smoothing
+type NaiveBayes struct {
+	Classes     []string                  `json:"classes"`
+	DocCounts   map[string]int            `json:"doc_counts"`
+	TermCounts  map[string]map[string]int `json:"term_counts"`
+	TotalTerms  map[string]int            `json:"total_terms"`
+	Vocabulary  int                       `json:"vocabulary"`
+	TrainedDocs int                       `json:"trained_docs"`
+}
+
+// LabelledArticle is a training example
+type LabelledArticle struct {
+	Article  models.Article
+	Category string
+}
+
+// TrainingSet returns the articles with a primary canonical category
+func TrainingSet(articles []models.Article) []LabelledArticle {
+	out := []LabelledArticle{}
+	for _, a := range articles {
+		if c := PrimaryCategory(a.Category); c != "" {
+			out = append(out, LabelledArticle{Article: a, Category: c})
+		}
+	}
+	return out
+}
+
+func classifierTokens(a models.Article) []string {
+	// title words twice, like the TF-IDF index
+	title := Tokenize(a.Title)
+	return append(append(title, title...), Tokenize(a.Description)...)
+}
+
+// TrainNaiveBayes fits a model on labelled articles
+func TrainNaiveBayes(examples []LabelledArticle) *NaiveBayes {
+	nb := &NaiveBayes{
+		DocCounts:  map[string]int{},
+		TermCounts: map[string]map[string]int{},
+		TotalTerms: map[string]int{},
+	}
+	vocab := map[string]bool{}
+	for _, ex := range examples {
+		if _, ok := nb.TermCounts[ex.Category]; !ok {
+			nb.TermCounts[ex.Category] = map[string]int{}
+			nb.Classes = append(nb.Classes, ex.Category)
+		}
+		nb.DocCounts[ex.Category]++
+		for _, t := range classifierTokens(ex.Article) {
+			nb.TermCounts[ex.Category][t]++
+			nb.TotalTerms[ex.Category]++
+			vocab[t] = true
+		}
+	}
+	sort.Strings(nb.Classes)
+	nb.Vocabulary = len(vocab)
+	nb.TrainedDocs = len(examples)
+	return nb
+}
+
+// CategoryScore is a class with its posterior probability
+type CategoryScore struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Predict returns all classes ordered by posterior probability
+func (nb *NaiveBayes) Predict(a models.Article) []CategoryScore {
+	tokens := classifierTokens(a)
+	logs := make([]float64, len(nb.Classes))
+	maxLog := math.Inf(-1)
+	for i, c := range nb.Classes {
+		lp := math.Log(float64(nb.DocCounts[c]) / float64(nb.TrainedDocs))
+		denom := float64(nb.TotalTerms[c] + nb.Vocabulary)
+		for _, t := range tokens {
+			lp += math.Log(float64(nb.TermCounts[c][t]+1) / denom)
+		}
+		logs[i] = lp
+		maxLog = math.Max(maxLog, lp)
+	}
+	// softmax over log posteriors
+	sum := 0.0
+	out := make([]CategoryScore, len(nb.Classes))
+	for i, c := range nb.Classes {
+		p := math.Exp(logs[i] - maxLog)
+		sum += p
+		out[i] = CategoryScore{Category: c, Confidence: p}
+	}
+	for i := range out {
+		out[i].Confidence /= sum
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Confidence > out[j].Confidence
+	})
+	return out
+}
+
+// Save writes the model as JSON
+func (nb *NaiveBayes) Save(path string) error {
+	b, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+// LoadNaiveBayes reads a model written by Save
+func LoadNaiveBayes(path string) (*NaiveBayes, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var nb NaiveBayes
+	if err := json.Unmarshal(b, &nb); err != nil {
+		return nil, err
+	}
+	if nb.TrainedDocs == 0 || len(nb.Classes) == 0 {
+		return nil, errors.New("empty category model")
+	}
+	return &nb, nil
+}
+
+// CategoryMetrics are per-class evaluation results
+type CategoryMetrics struct {
+	Category  string  `json:"category"`
+	Support   int     `json:"support"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// EvaluateClassifier scores predictions against the primary labels of examples
+func EvaluateClassifier(predict func(models.Article) string, examples []LabelledArticle) (accuracy float64, metrics []CategoryMetrics) {
+	tp, fp, support := map[string]int{}, map[string]int{}, map[string]int{}
+	correct := 0
+	for _, ex := range examples {
+		got := predict(ex.Article)
+		support[ex.Category]++
+		if got == ex.Category {
+			tp[got]++
+			correct++
+		} else {
+			fp[got]++
+		}
+	}
+	for _, c := range CanonicalCategories {
+		if support[c] == 0 && fp[c] == 0 {
+			continue
+		}
+		m := CategoryMetrics{Category: c, Support: support[c]}
+		if tp[c]+fp[c] > 0 {
+			m.Precision = float64(tp[c]) / float64(tp[c]+fp[c])
+		}
+		if support[c] > 0 {
+			m.Recall = float64(tp[c]) / float64(support[c])
+		}
+		if m.Precision+m.Recall > 0 {
+			m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+		}
+		metrics = append(metrics, m)
+	}
+	if len(examples) > 0 {
+		accuracy = float64(correct) / float64(len(examples))
+	}
+	return accuracy, metrics
+}
+
+// minimum naive Bayes confidence before the LLM provider is consulted
+const llmClassifyBelow = 0.6
+
+var (
+	classifierMu sync.RWMutex
+	classifier   *NaiveBayes
+)
+
+// EnsureClassifier loads the model from CATEGORY_MODEL_PATH when set, or
+// trains one on the labelled articles given. It is a no-op once a model exists.
+func EnsureClassifier(articles []models.Article) {
+	classifierMu.Lock()
+	defer classifierMu.Unlock()
+	if classifier != nil {
+		return
+	}
+	if path := config.Get().Data.CategoryModelPath; path != "" {
+		nb, err := LoadNaiveBayes(path)
+		if err == nil {
+			classifier = nb
+			return
+		}
+		slog.Warn("load category model, training instead", "path", path, "error", err)
+	}
+	examples := TrainingSet(articles)
+	if len(examples) == 0 {
+		return
+	}
+	classifier = TrainNaiveBayes(examples)
+	slog.Info("trained category classifier", "examples", len(examples))
+}
+
+// ClassifyArticle predicts a canonical category for an article, asking the LLM
+// provider when one is configured and the local model is unsure.
+func ClassifyArticle(ctx context.Context, a models.Article) (*models.CategoryPrediction, error) {
+	classifierMu.RLock()
+	nb := classifier
+	classifierMu.RUnlock()
+	if nb == nil {
+		return nil, errors.New("category classifier not trained")
+	}
+	scores := nb.Predict(a)
+	pred := &models.CategoryPrediction{Category: scores[0].Category, Confidence: scores[0].Confidence, Model: "naive-bayes"}
+	if pred.Confidence < llmClassifyBelow && llmConfigured() {
+		cat, err := llmClassify(ctx, a)
+		if err != nil {
+			slog.ErrorContext(ctx, "llm classify", "error", err)
+		} else if cat != "" {
+			pred = &models.CategoryPrediction{Category: cat, Confidence: 0.9, Model: "llm"}
+		}
+	}
+	return pred, nil
+}
+
+// ClassifyArticles sets AutoCategory on uncategorised articles in place and
+// returns how many were classified.
+func ClassifyArticles(ctx context.Context, articles []models.Article) int {
+	n := 0
+	for i := range articles {
+		if !NeedsCategory(articles[i]) || articles[i].AutoCategory != nil {
+			continue
+		}
+		pred, err := ClassifyArticle(ctx, articles[i])
+		if err != nil {
+			slog.ErrorContext(ctx, "classify article", "error", err)
+			return n
+		}
+		articles[i].AutoCategory = pred
+		n++
+	}
+	return n
+}
+
+// ClassifyStoredArticles classifies stored uncategorised articles and writes
+// the predictions back.
+func ClassifyStoredArticles(ctx context.Context, articles []models.Article) error {
+	pending := []models.Article{}
+	for _, a := range articles {
+		if NeedsCategory(a) && a.AutoCategory == nil {
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) == 0 || articlesColl == nil {
+		return nil
+	}
+	ClassifyArticles(ctx, pending)
+	writes := []mongo.WriteModel{}
+	for _, a := range pending {
+		if a.AutoCategory == nil {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": a.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"auto_category": a.AutoCategory}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err := articlesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// llmClassify asks the chat model to pick one canonical category. Answers
+// outside the canonical set are discarded.
+func llmClassify(ctx context.Context, a models.Article) (string, error) {
+	prompt := fmt.Sprintf("Classify this news article into exactly one of: %s.\nAnswer with the category only.\n\nTitle: %s\nDescription: %s",
+		strings.Join(CanonicalCategories, ", "), a.Title, a.Description)
+	answer, err := llmChat(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if c, ok := CanonicalCategory(strings.Trim(strings.ToLower(answer), ".\"'")); ok {
+		return c, nil
+	}
+	return "", nil
+}
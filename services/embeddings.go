@@ -0,0 +1,467 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/metrics"
+	"news-backend/models"
+	"news-backend/tracing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Embedder turns texts into fixed-size vectors
+type Embedder interface {
+	// Name identifies the model; stored vectors are only reused for the same name
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HashingEmbedder is an offline fallback using the hashing trick over word
+// unigrams, bigrams and character trigrams. It needs no model files and gives
+// useful lexical-semantic overlap, though not true synonymy.
+type HashingEmbedder struct {
+	Dim int
+}
+
+func (h HashingEmbedder) Name() string {
+	return fmt.Sprintf("hashing-%d", h.Dim)
+}
+
+func (h HashingEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		out[i] = h.embedOne(t)
+	}
+	return out, nil
+}
+
+func (h HashingEmbedder) embedOne(text string) []float32 {
+	v := make([]float64, h.Dim)
+	add := func(feature string, w float64) {
+		f := fnv.New64a()
+		f.Write([]byte(feature))
+		sum := f.Sum64()
+		// one bit of the hash picks the sign so collisions cancel out on average
+		sign := 1.0
+		if sum>>63 == 1 {
+			sign = -1.0
+		}
+		v[sum%uint64(h.Dim)] += sign * w
+	}
+	tokens := Tokenize(text)
+	for i, t := range tokens {
+		add("w:"+t, 1)
+		if i > 0 {
+			add("b:"+tokens[i-1]+"_"+t, 0.5)
+		}
+		r := []rune("#" + t + "#")
+		for j := 0; j+3 <= len(r); j++ {
+			add("c:"+string(r[j:j+3]), 0.25)
+		}
+	}
+	return normalize(v)
+}
+
+func normalize(v []float64) []float32 {
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint
+type HTTPEmbedder struct {
+	URL    string
+	APIKey string
+	Model  string
+	Client *http.Client
+}
+
+func (h HTTPEmbedder) Name() string {
+	return h.Model
+}
+
+func (h HTTPEmbedder) Embed(ctx context.Context, texts []string) (vecs [][]float32, err error) {
+	ctx, span := tracing.Start(ctx, "llm.embeddings", attribute.String("llm.model", h.Model), attribute.Int("llm.inputs", len(texts)))
+	start := time.Now()
+	var usage llmUsage
+	defer func() {
+		metrics.LLMCall("embeddings", h.Model, time.Since(start), usage.PromptTokens, 0, err)
+		span.SetAttributes(attribute.Int("llm.prompt_tokens", usage.PromptTokens))
+		tracing.End(span, err)
+	}()
+	body, err := json.Marshal(map[string]interface{}{"model": h.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings provider returned %s", resp.Status)
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage llmUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	usage = out.Usage
+	if len(out.Data) != len(texts) {
+		return nil, errors.New("embeddings provider returned wrong number of vectors")
+	}
+	vecs = make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, errors.New("embeddings provider returned bad index")
+		}
+		vecs[d.Index] = normalize(d.Embedding)
+	}
+	return vecs, nil
+}
+
+// stored vector document
+type articleEmbedding struct {
+	ArticleID string    `bson:"article_id"`
+	Model     string    `bson:"model"`
+	Vector    []float32 `bson:"vector"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+const (
+	embedBatchSize       = 64
+	embeddingsCollection = "article_embeddings"
+
+	// a failing provider is skipped for providerRetryMin, doubling with each
+	// further failure up to providerRetryMax, then tried again
+	providerRetryMin = 30 * time.Second
+	providerRetryMax = 10 * time.Minute
+)
+
+var (
+	embeddingsColl *mongo.Collection
+
+	// vectorsMu guards the provider and its breaker too
+	vectorsMu sync.RWMutex
+	hashing   = HashingEmbedder{Dim: 256}
+	provider  Embedder // nil without embeddings.api_url
+	breaker   providerBreaker
+	// vectors by model name, then article ID; the provider's are kept while
+	// the hashing model stands in for it
+	vectors = map[string]map[string][]float32{}
+)
+
+// providerBreaker tracks provider failures: after one, calls go to the
+// hashing model until retryAt, and the next call after that tries the
+// provider again
+type providerBreaker struct {
+	failures int
+	retryAt  time.Time
+}
+
+// currentEmbedder is the provider unless it failed recently, else the
+// hashing model
+func currentEmbedder() Embedder {
+	vectorsMu.RLock()
+	defer vectorsMu.RUnlock()
+	if provider != nil && !time.Now().Before(breaker.retryAt) {
+		return provider
+	}
+	return hashing
+}
+
+// fallBackToHashing records a failure of e and reports whether the caller
+// should retry with the hashing model, which it should unless e is the
+// hashing model itself
+func fallBackToHashing(ctx context.Context, e Embedder, err error) bool {
+	if e == Embedder(hashing) {
+		return false
+	}
+	vectorsMu.Lock()
+	defer vectorsMu.Unlock()
+	breaker.failures++
+	wait := providerRetryMax
+	if breaker.failures <= 6 {
+		wait = min(providerRetryMin<<(breaker.failures-1), providerRetryMax)
+	}
+	breaker.retryAt = time.Now().Add(wait)
+	slog.WarnContext(ctx, "embeddings provider failed, using the hashing model", "model", e.Name(), "retry_in", wait, "error", err)
+	return true
+}
+
+// providerSucceeded closes the breaker after a successful call to e
+func providerSucceeded(ctx context.Context, e Embedder) {
+	if e == Embedder(hashing) {
+		return
+	}
+	vectorsMu.Lock()
+	defer vectorsMu.Unlock()
+	if breaker.failures > 0 {
+		slog.InfoContext(ctx, "embeddings provider recovered", "model", e.Name())
+		breaker = providerBreaker{}
+	}
+}
+
+// storedVector returns the vector of an article under the current model
+func storedVector(id string) []float32 {
+	name := currentEmbedder().Name()
+	vectorsMu.RLock()
+	defer vectorsMu.RUnlock()
+	return vectors[name][id]
+}
+
+// InitEmbeddings selects the embedder (embeddings.api_url enables the provider,
+// otherwise the offline hashing model) and loads stored vectors into memory.
+func InitEmbeddings(client *mongo.Client, dbName string) {
+	var p Embedder
+	names := []string{hashing.Name()}
+	if cfg := config.Get().Embeddings; cfg.APIURL != "" {
+		model := cfg.Model
+		if model == "" {
+			model = "text-embedding-3-small"
+		}
+		p = HTTPEmbedder{
+			URL:    cfg.APIURL,
+			APIKey: cfg.APIKey,
+			Model:  model,
+			Client: &http.Client{Timeout: 30 * time.Second, Transport: tracing.Transport(nil)},
+		}
+		names = append(names, p.Name())
+	}
+	embeddingsColl = client.Database(dbName).Collection(embeddingsCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := embeddingsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "article_id", Value: 1}, {Key: "model", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		slog.ErrorContext(ctx, "embeddings indexes", "error", err)
+	}
+	cur, err := embeddingsColl.Find(ctx, bson.M{"model": bson.M{"$in": names}})
+	if err != nil {
+		slog.ErrorContext(ctx, "load embeddings", "error", err)
+		vectorsMu.Lock()
+		provider = p
+		vectorsMu.Unlock()
+		return
+	}
+	defer cur.Close(ctx)
+	loaded := map[string]map[string][]float32{}
+	for cur.Next(ctx) {
+		var e articleEmbedding
+		if err := cur.Decode(&e); err == nil {
+			if loaded[e.Model] == nil {
+				loaded[e.Model] = map[string][]float32{}
+			}
+			loaded[e.Model][e.ArticleID] = e.Vector
+		}
+	}
+	vectorsMu.Lock()
+	provider, vectors = p, loaded
+	vectorsMu.Unlock()
+}
+
+func embeddingText(a models.Article) string {
+	return a.Title + ". " + a.Description
+}
+
+// EnsureEmbeddings computes and stores vectors for articles that have none yet
+// and returns the vectors of all given articles, all from one model. While
+// the provider is failing the hashing model stands in for it rather than
+// failing the caller.
+func EnsureEmbeddings(ctx context.Context, articles []models.Article) (map[string][]float32, error) {
+	e := currentEmbedder()
+	out, err := ensureEmbeddings(ctx, e, articles)
+	if err != nil && fallBackToHashing(ctx, e, err) {
+		return ensureEmbeddings(ctx, hashing, articles)
+	}
+	if err == nil {
+		providerSucceeded(ctx, e)
+	}
+	return out, err
+}
+
+func ensureEmbeddings(ctx context.Context, e Embedder, articles []models.Article) (map[string][]float32, error) {
+	vectorsMu.RLock()
+	missing := []models.Article{}
+	for _, a := range articles {
+		if _, ok := vectors[e.Name()][a.ID]; !ok {
+			missing = append(missing, a)
+		}
+	}
+	vectorsMu.RUnlock()
+
+	for start := 0; start < len(missing); start += embedBatchSize {
+		batch := missing[start:min(start+embedBatchSize, len(missing))]
+		texts := make([]string, len(batch))
+		for i, a := range batch {
+			texts[i] = embeddingText(a)
+		}
+		vecs, err := e.Embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if err := storeEmbeddings(ctx, e, batch, vecs); err != nil {
+			// vectors are still usable from memory; persisting only saves recomputation
+			slog.ErrorContext(ctx, "store embeddings", "error", err)
+		}
+		vectorsMu.Lock()
+		if vectors[e.Name()] == nil {
+			vectors[e.Name()] = map[string][]float32{}
+		}
+		for i, a := range batch {
+			vectors[e.Name()][a.ID] = vecs[i]
+		}
+		vectorsMu.Unlock()
+	}
+
+	out := make(map[string][]float32, len(articles))
+	vectorsMu.RLock()
+	for _, a := range articles {
+		out[a.ID] = vectors[e.Name()][a.ID]
+	}
+	vectorsMu.RUnlock()
+	return out, nil
+}
+
+func storeEmbeddings(ctx context.Context, e Embedder, batch []models.Article, vecs [][]float32) error {
+	if embeddingsColl == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	writes := make([]mongo.WriteModel, len(batch))
+	for i, a := range batch {
+		writes[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"article_id": a.ID, "model": e.Name()}).
+			SetReplacement(articleEmbedding{ArticleID: a.ID, Model: e.Name(), Vector: vecs[i], UpdatedAt: now}).
+			SetUpsert(true)
+	}
+	_, err := embeddingsColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// forgetEmbeddings deletes the vectors of articles whose text changed, from
+// coll and from memory, for every model
+func forgetEmbeddings(ctx context.Context, coll *mongo.Collection, ids []string) error {
+	vectorsMu.Lock()
+	for _, byID := range vectors {
+		for _, id := range ids {
+			delete(byID, id)
+		}
+	}
+	vectorsMu.Unlock()
+	_, err := coll.DeleteMany(ctx, bson.M{"article_id": bson.M{"$in": ids}})
+	return err
+}
+
+// EmbedQuery returns the vector for a free-text query, falling back to the
+// hashing model like EnsureEmbeddings
+func EmbedQuery(ctx context.Context, q string) ([]float32, error) {
+	e := currentEmbedder()
+	vecs, err := e.Embed(ctx, []string{q})
+	if err != nil && fallBackToHashing(ctx, e, err) {
+		vecs, err = hashing.Embed(ctx, []string{q})
+	} else if err == nil {
+		providerSucceeded(ctx, e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// ScoredArticle is an article with a ranking score
+type ScoredArticle struct {
+	Article models.Article
+	Score   float64
+}
+
+// SemanticRank orders articles by cosine similarity to the query vector,
+// dropping those with no positive similarity
+func SemanticRank(qv []float32, articles []models.Article, vecs map[string][]float32) []ScoredArticle {
+	out := []ScoredArticle{}
+	for _, a := range articles {
+		v := vecs[a.ID]
+		if len(v) != len(qv) {
+			continue
+		}
+		s := 0.0
+		for i := range v {
+			s += float64(v[i]) * float64(qv[i])
+		}
+		if s > 0 {
+			out = append(out, ScoredArticle{Article: a, Score: s})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
+
+// rrfK dampens the contribution of top ranks in reciprocal-rank fusion
+const rrfK = 60.0
+
+// FuseRankings merges ranked lists with reciprocal-rank fusion:
+// score(d) = sum over lists of 1 / (rrfK + rank(d)).
+func FuseRankings(lists ...[]ScoredArticle) []ScoredArticle {
+	scores := map[string]float64{}
+	byID := map[string]models.Article{}
+	for _, list := range lists {
+		for rank, s := range list {
+			scores[s.Article.ID] += 1 / (rrfK + float64(rank+1))
+			byID[s.Article.ID] = s.Article
+		}
+	}
+	out := make([]ScoredArticle, 0, len(scores))
+	for id, s := range scores {
+		out = append(out, ScoredArticle{Article: byID[id], Score: s})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score == out[j].Score {
+			return strings.Compare(out[i].Article.ID, out[j].Article.ID) < 0
+		}
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
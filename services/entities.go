@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"news-backend/config"
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maximum words in a gazetteer alias or capitalised name
+const maxEntityWords = 3
+
+var (
+	entitiesColl *mongo.Collection
+
+	// lowercase alias -> entity
+	entityAliases = map[string]models.Entity{}
+
+	// every entity by mention count, reused for cache.entities_ttl
+	topEntitiesMu sync.Mutex
+	topEntities   []EntityCount
+	topEntitiesAt time.Time
+)
+
+// words that never start or form part of an unknown capitalised name
+var entityNoise = map[string]bool{
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true,
+	"saturday": true, "sunday": true, "january": true, "february": true, "march": true,
+	"april": true, "may": true, "june": true, "july": true, "august": true, "september": true,
+	"october": true, "november": true, "december": true, "jan": true, "feb": true, "mar": true,
+	"apr": true, "jun": true, "jul": true, "aug": true, "sep": true, "oct": true, "nov": true, "dec": true,
+	"minister": true, "president": true, "deputy": true, "chief": true, "prime": true, "cm": true,
+	"pm": true, "justice": true, "mla": true, "mlas": true, "mp": true, "ceo": true, "chairman": true,
+	"secretary": true, "police": true, "report": true, "reports": true, "day": true, "news": true,
+	"prediction": true, "tarot": true, "card": true, "zodiac": true, "astrology": true, "rashifal": true,
+	"short": true, "shorts": true, "big": true, "watch": true, "video": true, "live": true, "update": true,
+	"updates": true, "explained": true, "however": true, "according": true, "despite": true,
+	"while": true, "during": true, "i'm": true, "it's": true, "top": true, "former": true,
+	"union": true, "home": true, "general": true, "opposition": true, "singer": true, "actor": true,
+	"actress": true, "director": true, "leader": true, "captain": true, "coach": true, "judge": true,
+	"mr": true, "mrs": true, "dr": true, "indian": true, "palestinian": true, "israeli": true,
+	"chinese": true, "pakistani": true, "american": true, "russian": true, "ukrainian": true,
+	"british": true, "bangladeshi": true, "hospital": true,
+}
+
+var (
+	orgSuffixes = map[string]bool{
+		"party": true, "bank": true, "ministry": true, "university": true, "corporation": true,
+		"court": true, "board": true, "army": true, "department": true, "limited": true, "ltd": true,
+		"inc": true, "institute": true, "commission": true, "council": true, "company": true,
+		"group": true, "federation": true, "association": true, "authority": true, "hospital": true,
+	}
+	placeSuffixes = map[string]bool{
+		"pradesh": true, "nagar": true, "city": true, "district": true, "island": true,
+		"islands": true, "valley": true, "river": true, "state": true, "village": true,
+	}
+	personTitles = map[string]bool{
+		"minister": true, "president": true, "cm": true, "pm": true, "justice": true, "mla": true,
+		"mp": true, "ceo": true, "actor": true, "actress": true, "director": true, "leader": true,
+		"chairman": true, "secretary": true, "judge": true, "captain": true, "coach": true,
+		"singer": true, "mr": true, "mrs": true, "ms": true, "dr": true,
+	}
+)
+
+func init() {
+	for _, g := range gazetteer {
+		e := models.Entity{ID: EntityID(g.Name), Name: g.Name, Type: g.Type}
+		entityAliases[strings.ToLower(g.Name)] = e
+		for _, a := range g.Aliases {
+			entityAliases[a] = e
+		}
+	}
+}
+
+// EntityID normalizes a name into a stable slug: "Narendra Modi" -> "narendra-modi"
+func EntityID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// entityWord is a word of the original text with its position context
+type entityWord struct {
+	raw           string // original casing, punctuation trimmed
+	lower         string
+	sentenceStart bool
+	breakAfter    bool // punctuation ends a name after this word
+}
+
+func entityWords(text string) []entityWord {
+	text = strings.NewReplacer("’", "'", "‘", "'").Replace(text)
+	out := []entityWord{}
+	sentenceStart := true
+	for _, f := range strings.Fields(text) {
+		trimmed := strings.TrimFunc(f, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '&'
+		})
+		// possessives name the same entity
+		trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "'s"), "'")
+		last := f[len(f)-1]
+		if trimmed != "" {
+			out = append(out, entityWord{
+				raw:           trimmed,
+				lower:         strings.ToLower(trimmed),
+				sentenceStart: sentenceStart,
+				breakAfter:    strings.ContainsRune(",.:;!?\"')|", rune(last)),
+			})
+		}
+		sentenceStart = last == '.' || last == '!' || last == '?' || last == ':' || last == '|'
+	}
+	return out
+}
+
+// aliasMatches reports whether the words spell alias. Short all-letter aliases
+// (us, up, ed, sc) only match when written in capitals, so pronouns and
+// prepositions are not mistaken for entities.
+func aliasMatches(words []entityWord, alias string) bool {
+	if len(words) != 1 || len(alias) > 3 {
+		return true
+	}
+	for _, r := range alias {
+		if !unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return words[0].raw == strings.ToUpper(words[0].raw)
+}
+
+// ExtractEntities finds people, organisations and places in text using the
+// gazetteer first and capitalised multi-word names second. Unknown names get
+// a type from their context (titles before, suffixes like "Party" after) or
+// EntityOther. Title Case text only uses the gazetteer, since capitals carry
+// no signal there.
+func ExtractEntities(text string) []models.Entity {
+	out := []models.Entity{}
+	extractEntities(text, map[string]bool{}, &out)
+	return out
+}
+
+func extractEntities(text string, seen map[string]bool, out *[]models.Entity) {
+	words := entityWords(text)
+	titleCase := isTitleCase(words)
+	add := func(e models.Entity) {
+		if e.ID != "" && !seen[e.ID] {
+			seen[e.ID] = true
+			*out = append(*out, e)
+		}
+	}
+	for i := 0; i < len(words); {
+		// longest gazetteer alias starting here
+		matched := 0
+		for n := min(maxEntityWords, len(words)-i); n >= 1; n-- {
+			parts := make([]string, n)
+			for k := 0; k < n; k++ {
+				parts[k] = words[i+k].lower
+			}
+			alias := strings.Join(parts, " ")
+			if e, ok := entityAliases[alias]; ok && aliasMatches(words[i:i+n], alias) {
+				add(e)
+				matched = n
+				break
+			}
+		}
+		if matched > 0 {
+			i += matched
+			continue
+		}
+		if titleCase {
+			i++
+			continue
+		}
+		// run of capitalised words forming an unknown name
+		n := 0
+		for i+n < len(words) && n < maxEntityWords && isNameWord(words[i+n]) {
+			n++
+			if words[i+n-1].breakAfter {
+				break
+			}
+		}
+		if n >= 2 && !(words[i].sentenceStart && n == 2 && stopWords[words[i].lower]) {
+			parts := make([]string, n)
+			for k := 0; k < n; k++ {
+				parts[k] = words[i+k].raw
+			}
+			name := strings.Join(parts, " ")
+			add(models.Entity{ID: EntityID(name), Name: name, Type: guessEntityType(words, i, n)})
+			i += n
+			continue
+		}
+		i++
+	}
+}
+
+func isNameWord(w entityWord) bool {
+	r := []rune(w.raw)
+	if len(r) < 2 || !unicode.IsUpper(r[0]) {
+		return false
+	}
+	// "Oscar-winning" is an adjective, not part of a name
+	if i := strings.IndexRune(w.raw, '-'); i >= 0 && i+1 < len(w.raw) && unicode.IsLower(rune(w.raw[i+1])) {
+		return false
+	}
+	if w.lower == "new" {
+		return true
+	}
+	return !entityNoise[w.lower] && !stopWords[w.lower]
+}
+
+// isTitleCase reports whether most words are capitalised, as in many headlines
+func isTitleCase(words []entityWord) bool {
+	if len(words) < 4 {
+		return false
+	}
+	upper := 0
+	for _, w := range words {
+		if r := []rune(w.raw); unicode.IsUpper(r[0]) {
+			upper++
+		}
+	}
+	return float64(upper)/float64(len(words)) > 0.6
+}
+
+func guessEntityType(words []entityWord, i, n int) string {
+	last := words[i+n-1].lower
+	switch {
+	case orgSuffixes[last]:
+		return EntityOrganization
+	case placeSuffixes[last]:
+		return EntityPlace
+	case i > 0 && personTitles[words[i-1].lower]:
+		return EntityPerson
+	}
+	return EntityOther
+}
+
+// ArticleEntities extracts the entities of an article's title and description
+func ArticleEntities(a models.Article) []models.Entity {
+	out := []models.Entity{}
+	seen := map[string]bool{}
+	extractEntities(a.Title, seen, &out)
+	extractEntities(a.Description, seen, &out)
+	return out
+}
+
+// InitEntities wires the entity catalog. Articles are tagged in the
+// collection set up by InitArticles.
+func InitEntities(client *mongo.Client, dbName string) {
+	entitiesColl = client.Database(dbName).Collection("entities")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := entitiesColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		slog.ErrorContext(ctx, "entities indexes", "error", err)
+	}
+}
+
+// TagEntities fills in Entities for articles that have none yet and returns
+// the distinct entities found. It does not write to the database.
+func TagEntities(articles []models.Article) []models.Entity {
+	found := map[string]models.Entity{}
+	for i := range articles {
+		if articles[i].Entities != nil {
+			continue
+		}
+		ids := []string{}
+		for _, e := range ArticleEntities(articles[i]) {
+			ids = append(ids, e.ID)
+			found[e.ID] = e
+		}
+		articles[i].Entities = ids
+	}
+	out := make([]models.Entity, 0, len(found))
+	for _, e := range found {
+		out = append(out, e)
+	}
+	return out
+}
+
+// SaveEntityCatalog upserts entity names and types; existing entries are kept
+func SaveEntityCatalog(ctx context.Context, entities []models.Entity) error {
+	if entitiesColl == nil || len(entities) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, len(entities))
+	for i, e := range entities {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": e.ID}).
+			SetUpdate(bson.M{"$setOnInsert": e}).
+			SetUpsert(true)
+	}
+	_, err := entitiesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// TagStoredArticles extracts entities for stored articles that have none and
+// writes them back along with the catalog.
+func TagStoredArticles(ctx context.Context, articles []models.Article) error {
+	untagged := []models.Article{}
+	for _, a := range articles {
+		if a.Entities == nil {
+			untagged = append(untagged, a)
+		}
+	}
+	if len(untagged) == 0 || articlesColl == nil {
+		return nil
+	}
+	catalog := TagEntities(untagged)
+	writes := make([]mongo.WriteModel, len(untagged))
+	for i, a := range untagged {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": a.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"entities": a.Entities}})
+	}
+	if _, err := articlesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+	return SaveEntityCatalog(ctx, catalog)
+}
+
+// EntityCount is an entity with the number of articles mentioning it
+type EntityCount struct {
+	models.Entity `bson:",inline"`
+	Articles      int `bson:"articles" json:"articles"`
+}
+
+// GetEntity returns a catalog entry
+func GetEntity(ctx context.Context, id string) (models.Entity, error) {
+	var e models.Entity
+	err := entitiesColl.FindOne(ctx, bson.M{"id": id}).Decode(&e)
+	return e, err
+}
+
+// TopEntities returns the most mentioned entities, optionally of one type.
+// The counts cover every article, so they are cached for cache.entities_ttl.
+func TopEntities(ctx context.Context, entityType string, limit int) ([]EntityCount, error) {
+	topEntitiesMu.Lock()
+	defer topEntitiesMu.Unlock()
+	if topEntities == nil || time.Since(topEntitiesAt) >= config.Get().Cache.EntitiesTTL.D() {
+		counts, err := countEntities(ctx, bson.M{"entities.0": bson.M{"$exists": true}}, "")
+		if err != nil {
+			return nil, err
+		}
+		all, err := withCatalog(ctx, counts, "", 0)
+		if err != nil {
+			return nil, err
+		}
+		topEntities, topEntitiesAt = all, time.Now()
+	}
+	out := []EntityCount{}
+	for _, c := range topEntities {
+		if entityType != "" && c.Type != entityType {
+			continue
+		}
+		out = append(out, c)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// CoOccurringEntities returns the entities most often mentioned alongside id
+func CoOccurringEntities(ctx context.Context, id string, limit int) ([]EntityCount, error) {
+	counts, err := countEntities(ctx, bson.M{"entities": id}, id)
+	if err != nil {
+		return nil, err
+	}
+	return withCatalog(ctx, counts, "", limit)
+}
+
+// countEntities counts entity mentions over the articles matching filter, excluding one id
+func countEntities(ctx context.Context, filter bson.M, exclude string) ([]EntityCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$entities"}},
+		{{Key: "$match", Value: bson.M{"entities": bson.M{"$ne": exclude}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$entities", "articles": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "articles", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	cur, err := articlesColl.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := []EntityCount{}
+	for cur.Next(ctx) {
+		var r struct {
+			ID       string `bson:"_id"`
+			Articles int    `bson:"articles"`
+		}
+		if err := cur.Decode(&r); err == nil {
+			out = append(out, EntityCount{Entity: models.Entity{ID: r.ID}, Articles: r.Articles})
+		}
+	}
+	return out, cur.Err()
+}
+
+// withCatalog fills in names and types, filters by type and truncates to limit
+func withCatalog(ctx context.Context, counts []EntityCount, entityType string, limit int) ([]EntityCount, error) {
+	ids := make([]string, len(counts))
+	for i, c := range counts {
+		ids[i] = c.ID
+	}
+	cur, err := entitiesColl.Find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	catalog := map[string]models.Entity{}
+	for cur.Next(ctx) {
+		var e models.Entity
+		if err := cur.Decode(&e); err == nil {
+			catalog[e.ID] = e
+		}
+	}
+	out := []EntityCount{}
+	for _, c := range counts {
+		e, ok := catalog[c.ID]
+		if !ok {
+			e = models.Entity{ID: c.ID, Name: c.ID, Type: EntityOther}
+		}
+		if entityType != "" && e.Type != entityType {
+			continue
+		}
+		out = append(out, EntityCount{Entity: e, Articles: c.Articles})
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// EntityArticles returns a page of articles mentioning the entity, newest
+// first, optionally restricted to one language
+func EntityArticles(ctx context.Context, id, lang string, offset, limit int) ([]models.Article, int64, error) {
+	filter := bson.M{"entities": id}
+	if lang != "" {
+		filter["lang"] = lang
+	}
+	total, err := articlesColl.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cur, err := articlesColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cur.Close(ctx)
+	out := []models.Article{}
+	for cur.Next(ctx) {
+		var a models.Article
+		if err := cur.Decode(&a); err == nil {
+			a.Publication = models.ParsePublication(a.PublicationRaw)
+			out = append(out, a)
+		}
+	}
+	return out, total, cur.Err()
+}
@@ -0,0 +1,133 @@
+package services
+
+// Entity types
+const (
+	EntityPerson       = "person"
+	EntityOrganization = "organization"
+	EntityPlace        = "place"
+	EntityOther        = "other"
+)
+
+// gazetteerEntry is a known entity and the lowercase aliases it is written as
+type gazetteerEntry struct {
+	Name    string
+	Type    string
+	Aliases []string
+}
+
+// gazetteer lists entities common in our sources. Aliases are matched on word
+// boundaries; the canonical name is always an alias too.
+var gazetteer = []gazetteerEntry{
+	// people
+	{"Narendra Modi", EntityPerson, []string{"modi", "pm modi", "narendra modi"}},
+	{"Muhammad Yunus", EntityPerson, []string{"yunus", "muhammad yunus", "muhammed yunus"}},
+	{"Donald Trump", EntityPerson, []string{"trump", "donald trump"}},
+	{"Joe Biden", EntityPerson, []string{"biden", "joe biden"}},
+	{"Vladimir Putin", EntityPerson, []string{"putin", "vladimir putin"}},
+	{"Volodymyr Zelenskyy", EntityPerson, []string{"zelenskyy", "zelensky", "volodymyr zelenskyy"}},
+	{"Elon Musk", EntityPerson, []string{"musk", "elon musk"}},
+	{"Rahul Gandhi", EntityPerson, []string{"rahul gandhi"}},
+	{"Amit Shah", EntityPerson, []string{"amit shah"}},
+	{"Eknath Shinde", EntityPerson, []string{"shinde", "eknath shinde"}},
+	{"Devendra Fadnavis", EntityPerson, []string{"fadnavis", "devendra fadnavis"}},
+	{"Ajit Pawar", EntityPerson, []string{"ajit pawar"}},
+	{"Uddhav Thackeray", EntityPerson, []string{"uddhav thackeray", "uddhav"}},
+	{"Kunal Kamra", EntityPerson, []string{"kamra", "kunal kamra"}},
+	{"D K Shivakumar", EntityPerson, []string{"shivakumar", "dk shivakumar", "d k shivakumar"}},
+	{"Siddaramaiah", EntityPerson, []string{"siddaramaiah"}},
+	{"Yogi Adityanath", EntityPerson, []string{"yogi adityanath", "adityanath"}},
+	{"Nitish Kumar", EntityPerson, []string{"nitish kumar", "nitish"}},
+	{"M K Stalin", EntityPerson, []string{"stalin", "mk stalin", "m k stalin"}},
+	{"Mamata Banerjee", EntityPerson, []string{"mamata banerjee", "mamata"}},
+	{"Rekha Gupta", EntityPerson, []string{"rekha gupta"}},
+	{"Arvind Kejriwal", EntityPerson, []string{"kejriwal", "arvind kejriwal"}},
+	{"Nirmala Sitharaman", EntityPerson, []string{"sitharaman", "nirmala sitharaman"}},
+	{"Yashwant Varma", EntityPerson, []string{"yashwant varma", "justice varma"}},
+	{"Ekrem Imamoglu", EntityPerson, []string{"imamoglu", "ekrem imamoglu"}},
+	{"Benjamin Netanyahu", EntityPerson, []string{"netanyahu", "benjamin netanyahu"}},
+	{"Xi Jinping", EntityPerson, []string{"xi jinping"}},
+	{"MS Dhoni", EntityPerson, []string{"dhoni", "ms dhoni"}},
+	{"Virat Kohli", EntityPerson, []string{"kohli", "virat kohli"}},
+	{"Rohit Sharma", EntityPerson, []string{"rohit sharma"}},
+	{"Rishabh Pant", EntityPerson, []string{"pant", "rishabh pant"}},
+	{"Ajinkya Rahane", EntityPerson, []string{"rahane", "ajinkya rahane"}},
+	{"Shreyas Iyer", EntityPerson, []string{"shreyas iyer"}},
+	{"Ishan Kishan", EntityPerson, []string{"ishan kishan"}},
+	{"Ruturaj Gaikwad", EntityPerson, []string{"gaikwad", "ruturaj gaikwad"}},
+	{"Suryakumar Yadav", EntityPerson, []string{"suryakumar", "suryakumar yadav"}},
+	{"Sushant Singh Rajput", EntityPerson, []string{"sushant singh rajput", "sushant"}},
+	{"Rhea Chakraborty", EntityPerson, []string{"rhea chakraborty", "rhea"}},
+	{"Salman Khan", EntityPerson, []string{"salman khan", "salman"}},
+	{"Aamir Khan", EntityPerson, []string{"aamir khan", "aamir"}},
+	{"Sonu Sood", EntityPerson, []string{"sonu sood"}},
+
+	// organisations
+	{"Bharatiya Janata Party", EntityOrganization, []string{"bjp", "bharatiya janata party"}},
+	{"Indian National Congress", EntityOrganization, []string{"congress", "indian national congress"}},
+	{"Shiv Sena", EntityOrganization, []string{"shiv sena", "sena"}},
+	{"Aam Aadmi Party", EntityOrganization, []string{"aap", "aam aadmi party"}},
+	{"Trinamool Congress", EntityOrganization, []string{"tmc", "trinamool congress", "trinamool"}},
+	{"Supreme Court of India", EntityOrganization, []string{"supreme court", "sc"}},
+	{"Reserve Bank of India", EntityOrganization, []string{"rbi", "reserve bank of india"}},
+	{"SEBI", EntityOrganization, []string{"sebi"}},
+	{"ISRO", EntityOrganization, []string{"isro"}},
+	{"CBI", EntityOrganization, []string{"cbi", "central bureau of investigation"}},
+	{"Enforcement Directorate", EntityOrganization, []string{"enforcement directorate", "ed"}},
+	{"BCCI", EntityOrganization, []string{"bcci"}},
+	{"Indian Premier League", EntityOrganization, []string{"ipl", "indian premier league"}},
+	{"Kolkata Knight Riders", EntityOrganization, []string{"kkr", "kolkata knight riders"}},
+	{"Chennai Super Kings", EntityOrganization, []string{"csk", "chennai super kings"}},
+	{"Royal Challengers Bengaluru", EntityOrganization, []string{"rcb", "royal challengers bengaluru", "royal challengers bangalore"}},
+	{"Mumbai Indians", EntityOrganization, []string{"mumbai indians"}},
+	{"Sunrisers Hyderabad", EntityOrganization, []string{"srh", "sunrisers hyderabad"}},
+	{"Lucknow Super Giants", EntityOrganization, []string{"lsg", "lucknow super giants"}},
+	{"Delhi Capitals", EntityOrganization, []string{"delhi capitals"}},
+	{"Punjab Kings", EntityOrganization, []string{"pbks", "punjab kings"}},
+	{"Rajasthan Royals", EntityOrganization, []string{"rajasthan royals"}},
+	{"Gujarat Titans", EntityOrganization, []string{"gujarat titans"}},
+	{"Brihanmumbai Municipal Corporation", EntityOrganization, []string{"bmc", "brihanmumbai municipal corporation"}},
+	{"Indian Railways", EntityOrganization, []string{"indian railways", "railways"}},
+	{"United Nations", EntityOrganization, []string{"un", "united nations"}},
+	{"NATO", EntityOrganization, []string{"nato"}},
+	{"Hamas", EntityOrganization, []string{"hamas"}},
+	{"Tesla", EntityOrganization, []string{"tesla"}},
+	{"Instagram", EntityOrganization, []string{"instagram"}},
+	{"Google", EntityOrganization, []string{"google"}},
+	{"OpenAI", EntityOrganization, []string{"openai"}},
+
+	// places
+	{"India", EntityPlace, []string{"india"}},
+	{"Bangladesh", EntityPlace, []string{"bangladesh", "b'desh"}},
+	{"Pakistan", EntityPlace, []string{"pakistan", "pak"}},
+	{"China", EntityPlace, []string{"china"}},
+	{"United States", EntityPlace, []string{"us", "usa", "united states", "america"}},
+	{"United Kingdom", EntityPlace, []string{"uk", "united kingdom", "britain"}},
+	{"Russia", EntityPlace, []string{"russia"}},
+	{"Ukraine", EntityPlace, []string{"ukraine"}},
+	{"Israel", EntityPlace, []string{"israel"}},
+	{"Gaza", EntityPlace, []string{"gaza"}},
+	{"Yemen", EntityPlace, []string{"yemen"}},
+	{"Turkey", EntityPlace, []string{"turkey", "turkiye"}},
+	{"Istanbul", EntityPlace, []string{"istanbul"}},
+	{"Delhi", EntityPlace, []string{"delhi", "new delhi"}},
+	{"Mumbai", EntityPlace, []string{"mumbai"}},
+	{"Bengaluru", EntityPlace, []string{"bengaluru", "bangalore", "b'luru"}},
+	{"Hyderabad", EntityPlace, []string{"hyderabad"}},
+	{"Chennai", EntityPlace, []string{"chennai"}},
+	{"Kolkata", EntityPlace, []string{"kolkata"}},
+	{"Pune", EntityPlace, []string{"pune"}},
+	{"Lucknow", EntityPlace, []string{"lucknow"}},
+	{"Nagpur", EntityPlace, []string{"nagpur"}},
+	{"Maharashtra", EntityPlace, []string{"maharashtra", "maha"}},
+	{"Karnataka", EntityPlace, []string{"karnataka", "k'taka"}},
+	{"Tamil Nadu", EntityPlace, []string{"tamil nadu", "tn"}},
+	{"Kerala", EntityPlace, []string{"kerala"}},
+	{"Telangana", EntityPlace, []string{"telangana"}},
+	{"Bihar", EntityPlace, []string{"bihar"}},
+	{"Uttar Pradesh", EntityPlace, []string{"uttar pradesh", "up"}},
+	{"West Bengal", EntityPlace, []string{"west bengal", "wb", "bengal"}},
+	{"Punjab", EntityPlace, []string{"punjab"}},
+	{"Odisha", EntityPlace, []string{"odisha"}},
+	{"Jharkhand", EntityPlace, []string{"jharkhand", "j'khand"}},
+	{"Jammu and Kashmir", EntityPlace, []string{"jammu and kashmir", "j&k", "kashmir"}},
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"news-backend/config"
+	"news-backend/metrics"
+)
+
+var validEventTypes = map[string]bool{"view": true, "click": true, "share": true}
+
+// RecordEvent appends a real user event (view, click, share) to the trending event stream.
+func RecordEvent(e Event) error {
+	if e.ArticleID == "" {
+		return errors.New("article_id required")
+	}
+	if !validEventTypes[e.Type] {
+		return errors.New("event_type must be one of view, click, share")
+	}
+	if e.Ts.IsZero() {
+		e.Ts = time.Now()
+	}
+	eventsMu.Lock()
+	defer eventsMu.Unlock()
+	events = append(events, e)
+	metrics.EventIngested(e.Type)
+	// drop events that fell out of the trending.event_retention window
+	cutoff := time.Now().Add(-config.Get().Trending.EventRetention.D())
+	i := 0
+	for i < len(events) && events[i].Ts.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		events = append([]Event{}, events[i:]...)
+	}
+	return nil
+}
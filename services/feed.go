@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"news-backend/models"
+)
+
+// weights of the "For You" blend; they sum to 1
+const (
+	feedWeightPreference = 0.35
+	feedWeightRelevance  = 0.25
+	feedWeightTrending   = 0.20
+	feedWeightFreshness  = 0.20
+
+	// articles outside the user's language keep this share of their score
+	feedForeignLanguageFactor = 0.5
+
+	// freshness halves every feedFreshnessHalfLife
+	feedFreshnessHalfLife = 24 * time.Hour
+	// trending radius around the user's home location (km)
+	FeedTrendingRadius = 100.0
+)
+
+// FeedItem is an article with its personalised score
+type FeedItem struct {
+	Article models.Article
+	Score   float64
+}
+
+// RankFeed orders articles for a user by blending category and source
+// preferences, computed relevance, trending near home and freshness. Articles
+// in a language other than the user's are demoted. Muted sources and
+// already-read articles are dropped. trending maps article IDs to raw trending
+// scores near the user's home and may be nil.
+func RankFeed(articles []models.Article, u models.User, trending map[string]float64, read map[string]bool) []FeedItem {
+	preferred := lowerSet(u.PreferredCategories)
+	followed := lowerSet(u.FollowedSources)
+	muted := lowerSet(u.MutedSources)
+	lang := NormalizeLang(u.Language)
+
+	maxTrend := 0.0
+	for _, s := range trending {
+		maxTrend = math.Max(maxTrend, s)
+	}
+	// freshness is measured against the newest article so archived corpora still rank sensibly
+	var newest time.Time
+	for _, a := range articles {
+		if a.Publication.After(newest) {
+			newest = a.Publication
+		}
+	}
+
+	items := []FeedItem{}
+	for _, a := range articles {
+		if read[a.ID] || muted[strings.ToLower(a.SourceName)] {
+			continue
+		}
+		pref := 0.0
+		for _, c := range a.Category {
+			if preferred[strings.ToLower(c)] {
+				pref += 0.6
+				break
+			}
+		}
+		if followed[strings.ToLower(a.SourceName)] {
+			pref += 0.4
+		}
+		trend := 0.0
+		if maxTrend > 0 {
+			trend = trending[a.ID] / maxTrend
+		}
+		fresh := 0.0
+		if !a.Publication.IsZero() {
+			age := newest.Sub(a.Publication)
+			fresh = math.Exp(-math.Ln2 * age.Hours() / feedFreshnessHalfLife.Hours())
+		}
+		score := feedWeightPreference*pref +
+			feedWeightRelevance*RelevanceOf(a) +
+			feedWeightTrending*trend +
+			feedWeightFreshness*fresh
+		if lang != "" && ArticleLang(a) != lang {
+			score *= feedForeignLanguageFactor
+		}
+		items = append(items, FeedItem{Article: a, Score: score})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	return items
+}
+
+// TrendingScoresNear returns raw trending scores keyed by article ID around lat/lon
+func TrendingScoresNear(ctx context.Context, lat, lon, radius float64) map[string]float64 {
+	items, err := GetTrendingForLocation(ctx, lat, lon, radius, math.MaxInt32)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]float64, len(items))
+	for _, t := range items {
+		out[t.Article.ID] = t.Score
+	}
+	return out
+}
+
+func lowerSet(values []string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		out[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return out
+}
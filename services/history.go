@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrBookmarkNotFound = errors.New("bookmark not found")
+
+// progress updates within this long of the previous one belong to the same read
+const readViewWindow = 30 * time.Minute
+
+// RecordRead upserts a reading history entry, keeping the furthest progress
+// seen. newRead reports whether this starts a read: the first for the user
+// and article, or the first after readViewWindow without updates.
+func RecordRead(ctx context.Context, userID, articleID string, progress float64) (entry models.ReadEntry, newRead bool, err error) {
+	now := time.Now().UTC()
+	update := bson.M{
+		"$set": bson.M{"read_at": now},
+		"$max": bson.M{"progress": progress},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+	var before models.ReadEntry
+	err = historyColl.FindOneAndUpdate(ctx, bson.M{"user_id": userID, "article_id": articleID}, update, opts).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.ReadEntry{UserID: userID, ArticleID: articleID, Progress: progress, ReadAt: now}, true, nil
+	}
+	if err != nil {
+		return models.ReadEntry{}, false, err
+	}
+	entry = before
+	entry.Progress = max(before.Progress, progress)
+	entry.ReadAt = now
+	return entry, now.Sub(before.ReadAt) >= readViewWindow, nil
+}
+
+// ListReads returns a page of the user's history, most recent first, and the total count
+func ListReads(ctx context.Context, userID string, offset, limit int) ([]models.ReadEntry, int64, error) {
+	out := []models.ReadEntry{}
+	total, err := listPage(ctx, historyColl, userID, "read_at", offset, limit, &out)
+	return out, total, err
+}
+
+// AddBookmark saves an article for the user; saving twice keeps the original time
+func AddBookmark(ctx context.Context, userID, articleID string) (models.Bookmark, error) {
+	update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now().UTC()}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var out models.Bookmark
+	err := bookmarksColl.FindOneAndUpdate(ctx, bson.M{"user_id": userID, "article_id": articleID}, update, opts).Decode(&out)
+	return out, err
+}
+
+// ListBookmarks returns a page of the user's bookmarks, newest first, and the total count
+func ListBookmarks(ctx context.Context, userID string, offset, limit int) ([]models.Bookmark, int64, error) {
+	out := []models.Bookmark{}
+	total, err := listPage(ctx, bookmarksColl, userID, "created_at", offset, limit, &out)
+	return out, total, err
+}
+
+// RemoveBookmark deletes a saved article
+func RemoveBookmark(ctx context.Context, userID, articleID string) error {
+	res, err := bookmarksColl.DeleteOne(ctx, bson.M{"user_id": userID, "article_id": articleID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// listPage decodes one page of a user's documents sorted by sortField desc into out
+func listPage(ctx context.Context, coll *mongo.Collection, userID, sortField string, offset, limit int, out interface{}) (int64, error) {
+	filter := bson.M{"user_id": userID}
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField, Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+	return total, cur.All(ctx, out)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"news-backend/logging"
+)
+
+// indexBatchDelay lets queued IDs pile up so one run covers a whole import batch
+const indexBatchDelay = time.Second
+
+// articles waiting for the ingest pipeline (entities, category, embedding,
+// story); only queued while an indexer is running
+var (
+	indexMu      sync.Mutex
+	indexPending map[string]bool
+	indexWake    = make(chan struct{}, 1)
+)
+
+// StartIndexer runs index over queued article IDs until ctx is done. Before
+// it is called, and in newsctl, QueueIndex does nothing: the server's
+// startup run and its import watcher cover those articles.
+func StartIndexer(ctx context.Context, index func(context.Context, []string) error) {
+	indexMu.Lock()
+	indexPending = map[string]bool{}
+	indexMu.Unlock()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-indexWake:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(indexBatchDelay):
+			}
+			indexMu.Lock()
+			ids := make([]string, 0, len(indexPending))
+			for id := range indexPending {
+				ids = append(ids, id)
+			}
+			indexPending = map[string]bool{}
+			indexMu.Unlock()
+			if len(ids) > 0 {
+				_ = logging.Run(ctx, "index_new_articles", func(ctx context.Context) error {
+					return index(ctx, ids)
+				})
+			}
+		}
+	}()
+}
+
+// QueueIndex hands inserted or rewritten articles to the ingest pipeline
+func QueueIndex(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	indexMu.Lock()
+	if indexPending == nil {
+		indexMu.Unlock()
+		return
+	}
+	for _, id := range ids {
+		indexPending[id] = true
+	}
+	indexMu.Unlock()
+	select {
+	case indexWake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/language"
+)
+
+// LangUnknown is used when the text has too few letters to tell
+const LangUnknown = "und"
+
+// scripts used by our sources and the language each one most likely means.
+// Devanagari is shared by Hindi and Marathi and is told apart by stop words.
+var scriptLanguages = []struct {
+	Table *unicode.RangeTable
+	Lang  string
+}{
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Oriya, "or"},
+	{unicode.Arabic, "ur"},
+	{unicode.Latin, "en"},
+}
+
+// words far more common in Marathi than in Hindi
+var marathiMarkers = map[string]bool{
+	"आहे": true, "आणि": true, "आहेत": true, "झाली": true, "केली": true, "नाही": true, "त्यांनी": true, "होते": true,
+}
+
+// DetectLanguage returns the ISO 639-1 code of the dominant script in text
+func DetectLanguage(text string) string {
+	counts := make([]int, len(scriptLanguages))
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for i, s := range scriptLanguages {
+			if unicode.Is(s.Table, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+	if letters < 3 {
+		return LangUnknown
+	}
+	best := -1
+	for i, n := range counts {
+		if n > 0 && (best < 0 || n > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return LangUnknown
+	}
+	lang := scriptLanguages[best].Lang
+	if lang == "hi" {
+		markers := 0
+		for _, w := range tokenWords(text) {
+			if marathiMarkers[w] {
+				markers++
+			}
+		}
+		if markers >= 2 {
+			lang = "mr"
+		}
+	}
+	return lang
+}
+
+// ArticleLanguage detects the language of an article from its title and description
+func ArticleLanguage(a models.Article) string {
+	return DetectLanguage(a.Title + " " + a.Description)
+}
+
+// ArticleLang is the stored language of an article, detected when unset
+func ArticleLang(a models.Article) string {
+	if a.Lang != "" {
+		return a.Lang
+	}
+	return ArticleLanguage(a)
+}
+
+// DetectLanguages sets Lang on articles that have none, in place
+func DetectLanguages(articles []models.Article) {
+	for i := range articles {
+		if articles[i].Lang == "" {
+			articles[i].Lang = ArticleLanguage(articles[i])
+		}
+	}
+}
+
+// DetectStoredLanguages writes the detected language of stored articles that have none
+func DetectStoredLanguages(ctx context.Context, articles []models.Article) error {
+	writes := []mongo.WriteModel{}
+	for _, a := range articles {
+		if a.Lang != "" {
+			continue
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"id": a.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"lang": ArticleLanguage(a)}}))
+	}
+	if len(writes) == 0 || articlesColl == nil {
+		return nil
+	}
+	res, err := articlesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		slog.InfoContext(ctx, "detected article languages", "updated", res.ModifiedCount)
+	}
+	return err
+}
+
+// NormalizeLang reduces a language tag like "hi-IN" to its base code, or ""
+// when it is not a valid tag
+func NormalizeLang(tag string) string {
+	t, err := language.Parse(strings.TrimSpace(tag))
+	if err != nil {
+		return ""
+	}
+	base, _ := t.Base()
+	return base.String()
+}
+
+// PreferredLanguage returns the base code of the highest-weighted language in
+// an Accept-Language header, or "" when there is none
+func PreferredLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	base, conf := tags[0].Base()
+	if conf == language.No {
+		return ""
+	}
+	return base.String()
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/models"
+)
+
+// Subscription describes which articles a live feed client wants pushed to it.
+// Empty fields are ignored; an empty subscription matches everything.
+type Subscription struct {
+	Categories []string `json:"categories,omitempty"`
+	Sources    []string `json:"sources,omitempty"`
+	Query      string   `json:"query,omitempty"`
+	Lat        float64  `json:"lat,omitempty"`
+	Lon        float64  `json:"lon,omitempty"`
+	Radius     float64  `json:"radius,omitempty"` // km, only used when lat/lon set
+	Lang       string   `json:"lang,omitempty"`
+}
+
+// Matches reports whether the article satisfies every filter in the subscription.
+func (s Subscription) Matches(a models.Article) bool {
+	if len(s.Categories) > 0 && !containsFold(a.Category, s.Categories) {
+		return false
+	}
+	if len(s.Sources) > 0 && !containsFold([]string{a.SourceName}, s.Sources) {
+		return false
+	}
+	if s.Lang != "" && !strings.EqualFold(a.Lang, s.Lang) {
+		return false
+	}
+	if q := strings.TrimSpace(s.Query); q != "" {
+		text := strings.ToLower(a.Title + " " + a.Description)
+		if !strings.Contains(text, strings.ToLower(q)) {
+			return false
+		}
+	}
+	if s.Lat != 0 || s.Lon != 0 {
+		radius := s.Radius
+		if radius <= 0 {
+			radius = config.Get().Trending.DefaultRadius
+		}
+		if haversine(s.Lat, s.Lon, a.Latitude, a.Longitude) > radius {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(h, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// liveSeenWindow is how long a published article ID is remembered so
+// WatchImports does not push it a second time
+const liveSeenWindow = 5 * time.Minute
+
+// live article fan-out to connected feed clients
+var (
+	liveMu        sync.RWMutex
+	liveListeners = map[chan models.Article]struct{}{}
+
+	liveSeen = &recentKeys{window: liveSeenWindow, at: map[string]time.Time{}}
+)
+
+// SubscribeArticles registers a listener for newly published articles.
+// The returned cancel func must be called to release the listener.
+func SubscribeArticles(buffer int) (<-chan models.Article, func()) {
+	ch := make(chan models.Article, buffer)
+	liveMu.Lock()
+	liveListeners[ch] = struct{}{}
+	liveMu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			liveMu.Lock()
+			delete(liveListeners, ch)
+			liveMu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// PublishArticles pushes articles to every live listener, skipping those
+// published recently. Slow listeners whose buffer is full miss the article
+// rather than block ingest.
+func PublishArticles(articles []models.Article) {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	if len(liveListeners) == 0 {
+		return
+	}
+	articles = unseen(articles)
+	for ch := range liveListeners {
+		for _, a := range articles {
+			select {
+			case ch <- a:
+			default:
+			}
+		}
+	}
+}
+
+// unseen drops the articles published within liveSeenWindow and remembers the rest
+func unseen(articles []models.Article) []models.Article {
+	out := []models.Article{}
+	for _, a := range articles {
+		if liveSeen.add(a.ID) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// recentKeys remembers keys for window, so work done once is not repeated
+type recentKeys struct {
+	mu     sync.Mutex
+	window time.Duration
+	at     map[string]time.Time
+	pruned time.Time
+}
+
+// add remembers key and reports whether it was not remembered already
+func (r *recentKeys) add(key string) bool {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.pruned) > r.window {
+		for k, at := range r.at {
+			if now.Sub(at) > r.window {
+				delete(r.at, k)
+			}
+		}
+		r.pruned = now
+	}
+	if at, ok := r.at[key]; ok && now.Sub(at) <= r.window {
+		return false
+	}
+	r.at[key] = now
+	return true
+}
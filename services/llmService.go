@@ -2,9 +2,9 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log/slog"
 	"math"
 	"math/rand"
 	"os"
@@ -12,11 +12,13 @@ import (
 	"sync"
 	"time"
 
+	"news-backend/config"
+	"news-backend/logging"
+	"news-backend/metrics"
 	"news-backend/models"
+	"news-backend/tracing"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Event struct {
@@ -45,30 +47,50 @@ type cachedTrending struct {
 	Result []trendingItem
 }
 
-// InitTrendingSimulator loads articles and simulates events around them.
-func InitTrendingSimulator(client *mongo.Client, dbName, collName string) {
+// InitTrendingSimulator loads articles and simulates events around them in
+// the background, stopping early when ctx is done. Nothing is simulated when
+// trending.simulate is off.
+func InitTrendingSimulator(ctx context.Context) {
+	if !config.Get().Trending.Simulate {
+		// only real events feed trending, and the stream is ready as it is
+		SetTaskState(TaskTrending, TaskSkipped, nil)
+		return
+	}
+	SetTaskState(TaskTrending, TaskRunning, nil)
 	// generate initial simulated event stream stored in memory
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	logging.Go(ctx, "trending_simulator", func(ctx context.Context) (err error) {
+		defer func() {
+			if err != nil {
+				SetTaskState(TaskTrending, TaskFailed, err)
+			} else {
+				SetTaskState(TaskTrending, TaskDone, nil)
+			}
+		}()
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
-		coll := client.Database(dbName).Collection(collName)
-		cur, err := coll.Find(ctx, bson.M{})
+		articles, err := LoadAllArticles(ctx)
 		if err != nil {
-			return
-		}
-		defer cur.Close(ctx)
-		articles := []models.Article{}
-		for cur.Next(ctx) {
-			var a models.Article
-			if err := cur.Decode(&a); err == nil {
-				articles = append(articles, a)
-			}
+			return fmt.Errorf("load articles: %w", err)
 		}
 		simulateEvents(articles)
-	}()
+		return nil
+	})
+}
+
+// InitTrendingFromSnapshot simulates events over the article snapshot, for
+// when the database has been unreachable since startup. It does nothing once
+// events exist.
+func InitTrendingFromSnapshot() {
+	if !config.Get().Trending.Simulate || EventCount() > 0 {
+		return
+	}
+	articles, _ := ArticleSnapshot()
+	simulateEvents(articles)
+	SetTaskState(TaskTrending, TaskDone, nil)
 }
 
-// simulateEvents creates a list of events distributed among articles with timestamps over last 48h
+// simulateEvents creates a list of events distributed among articles with
+// timestamps within the event retention window
 func simulateEvents(articles []models.Article) {
 	eventsMu.Lock()
 	defer eventsMu.Unlock()
@@ -79,14 +101,15 @@ func simulateEvents(articles []models.Article) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	now := time.Now()
 	numEvents := 1000 + rnd.Intn(2000)
+	window := config.Get().Trending.EventRetention.D()
 	types := []string{"view", "click", "share"}
 	for i := 0; i < numEvents; i++ {
 		a := articles[rnd.Intn(len(articles))]
 		// jitter location slightly around article
 		lat := a.Latitude + (rnd.Float64()-0.5)*0.05
 		lon := a.Longitude + (rnd.Float64()-0.5)*0.05
-		// timestamp within last 48 hours, bias to recent
-		age := time.Duration(rnd.Intn(48*60)) * time.Minute
+		// timestamp within the retention window
+		age := time.Duration(rnd.Int63n(int64(window)))
 		ts := now.Add(-age)
 		events = append(events, Event{
 			ArticleID: a.ID,
@@ -96,17 +119,26 @@ func simulateEvents(articles []models.Article) {
 			Ts:        ts,
 		})
 	}
+	// keep the stream ordered by time so RecordEvent can trim from the front
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Ts.Before(events[j].Ts)
+	})
 }
 
 // GetTrendingForLocation computes trending articles near lat/lon within radius (km) and returns top limit results.
 // Uses a small cache keyed by rounded lat/lon+radius.
-func GetTrendingForLocation(lat, lon, radius float64, limit int) ([]trendingItem, error) {
+func GetTrendingForLocation(ctx context.Context, lat, lon, radius float64, limit int) (_ []trendingItem, err error) {
+	ctx, span := tracing.Start(ctx, "trending.compute",
+		attribute.Float64("geo.lat", lat), attribute.Float64("geo.lon", lon), attribute.Float64("trending.radius_km", radius))
+	defer func() { tracing.End(span, err) }()
 	key := cacheKey(lat, lon, radius)
 	// quick cached hit
 	cacheMu.RLock()
 	if e, ok := cache[key]; ok {
-		if time.Since(e.At) < 60*time.Second {
+		if time.Since(e.At) < config.Get().Cache.TrendingTTL.D() {
 			cacheMu.RUnlock()
+			metrics.TrendingCache(true)
+			span.SetAttributes(attribute.Bool("trending.cache_hit", true))
 			// return top limit
 			if len(e.Result) > limit {
 				return e.Result[:limit], nil
@@ -115,15 +147,19 @@ func GetTrendingForLocation(lat, lon, radius float64, limit int) ([]trendingItem
 		}
 	}
 	cacheMu.RUnlock()
+	metrics.TrendingCache(false)
+	span.SetAttributes(attribute.Bool("trending.cache_hit", false))
 
 	// compute trending
 	eventsMu.RLock()
 	evs := make([]Event, len(events))
 	copy(evs, events)
 	eventsMu.RUnlock()
+	span.SetAttributes(attribute.Int("trending.events", len(evs)))
 
 	// aggregate per article with recency decay and geo relevance
 	now := time.Now()
+	halfLife := config.Get().Trending.HalfLife.D().Hours()
 	scoreMap := map[string]float64{}
 	// weights for event types
 	weights := map[string]float64{"view": 1.0, "click": 2.0, "share": 3.0}
@@ -135,8 +171,8 @@ func GetTrendingForLocation(lat, lon, radius float64, limit int) ([]trendingItem
 			continue
 		}
 		ageHours := now.Sub(e.Ts).Hours()
-		// recency decay: half-life 12 hours -> weight multiply exp(-ln2 * age / 12)
-		decay := math.Exp(-math.Ln2 * ageHours / 12.0)
+		// recency decay: weight halves every trending.half_life
+		decay := math.Exp(-math.Ln2 * ageHours / halfLife)
 		w := weights[e.Type]
 		scoreMap[e.ArticleID] += w * decay * (1.0 / (1.0 + d)) // nearer events matter more
 	}
@@ -149,7 +185,7 @@ func GetTrendingForLocation(lat, lon, radius float64, limit int) ([]trendingItem
 	}
 	articles := []models.Article{}
 	if len(ids) > 0 {
-		articles = loadArticlesByIDs(ids)
+		articles = loadArticlesByIDs(ctx, ids)
 	}
 
 	// build items
@@ -183,26 +219,15 @@ func cacheKey(lat, lon, radius float64) string {
 	return fmt.Sprintf("%.2f:%.2f:%.1f", rlat, rlon, rr)
 }
 
-// loadArticlesByIDs reads DB and returns Articles for given ids
-func loadArticlesByIDs(ids []string) []models.Article {
-	// naive DB read: use global mongo client via services.GetMongoClient if present
-	// We call LoadNewsDataFromFile as fallback if DB not accessible
-	articles, err := LoadAllArticlesFromDB()
-	if err != nil {
-		// fallback
-		all, _ := LoadNewsDataFromFile("data/news_data.json")
-		idset := map[string]bool{}
-		for _, id := range ids {
-			idset[id] = true
+// loadArticlesByIDs reads DB and returns Articles for given ids, falling back
+// to the article snapshot while the database is unavailable
+func loadArticlesByIDs(ctx context.Context, ids []string) []models.Article {
+	if DatabaseAvailable() {
+		if articles, err := FindArticlesByIDs(ctx, ids); err == nil {
+			return articles
 		}
-		res := []models.Article{}
-		for _, a := range all {
-			if idset[a.ID] {
-				res = append(res, a)
-			}
-		}
-		return res
 	}
+	articles, _ := ArticleSnapshot()
 	idset := map[string]bool{}
 	for _, id := range ids {
 		idset[id] = true
@@ -216,37 +241,6 @@ func loadArticlesByIDs(ids []string) []models.Article {
 	return out
 }
 
-// helper to load all articles from DB using mongo client from controllers package
-func LoadAllArticlesFromDB() ([]models.Article, error) {
-	// Get MongoDB URI from environment variable or use default
-	mongoURI := os.Getenv("MONGODB_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://localhost:27017"
-	}
-
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
-	if err != nil {
-		return nil, err
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	defer client.Disconnect(ctx)
-	coll := client.Database("news").Collection("articles")
-	cur, err := coll.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cur.Close(ctx)
-	out := []models.Article{}
-	for cur.Next(ctx) {
-		var a models.Article
-		if err := cur.Decode(&a); err == nil {
-			out = append(out, a)
-		}
-	}
-	return out, nil
-}
-
 // reuse haversine from controllers
 func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 6371.0
@@ -260,20 +254,40 @@ func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 	return R * c
 }
 
-// LoadNewsDataFromFile reads data/news_data.json into []models.Article
+// LoadNewsDataFromFile reads a JSON (array) or JSON Lines file of articles.
+// The file is decoded as a stream; records that fail to decode or validate
+// are logged and skipped.
 func LoadNewsDataFromFile(path string) ([]models.Article, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	b, err := ioutil.ReadAll(f)
+	reader, err := NewArticleReader(f, format)
 	if err != nil {
 		return nil, err
 	}
-	var arr []models.Article
-	if err := json.Unmarshal(b, &arr); err != nil {
-		return nil, err
+	arr := []models.Article{}
+	for n := 1; ; n++ {
+		a, err := reader.Next()
+		if err == io.EOF {
+			return arr, nil
+		}
+		if rerr, ok := err.(*RecordError); ok {
+			slog.Warn("skipping article record", "file", path, "error", rerr.Error())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if problems := ValidateArticle(a); len(problems) > 0 {
+			slog.Warn("skipping article record", "file", path, "error", (&RecordError{Record: n, ID: a.ID, Errors: problems}).Error())
+			continue
+		}
+		arr = append(arr, a)
 	}
-	return arr, nil
 }
@@ -1,9 +1,22 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"news-backend/config"
+	"news-backend/metrics"
+	"news-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type QueryAnalysis struct {
@@ -81,6 +94,20 @@ func ExtractEntitiesAndIntent(query string) (QueryAnalysis, error) {
 		}
 	}
 
+	// Then names from the article entity gazetteer and capitalised names
+	for _, e := range ExtractEntities(query) {
+		found := false
+		for _, existing := range result.Entities {
+			if strings.EqualFold(existing, e.Name) || entityAliases[strings.ToLower(existing)].ID == e.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.Entities = append(result.Entities, e.Name)
+		}
+	}
+
 	// Then look for proper nouns (words that are capitalized in the original query)
 	words := strings.Fields(q)
 	for i, word := range words {
@@ -145,9 +172,121 @@ func GenerateSummary(title, description string) (string, error) {
 	return fmt.Sprintf("%s. %s", title, description), nil
 }
 
+var (
+	llmClientOnce sync.Once
+	llmClient     *http.Client
+)
+
+// llmConfigured reports whether an OpenAI-compatible chat endpoint is set up
+// (llm.api_url, with optional llm.api_key and llm.model)
+func llmConfigured() bool {
+	return config.Get().LLM.APIURL != ""
+}
+
+func llmHTTPClient() *http.Client {
+	llmClientOnce.Do(func() {
+		llmClient = &http.Client{Timeout: config.Get().LLM.Timeout.D(), Transport: tracing.Transport(nil)}
+	})
+	return llmClient
+}
+
+var (
+	llmProbeMu  sync.Mutex
+	llmProbeAt  time.Time
+	llmProbeErr error
+)
+
+// LLMReachable reports whether the configured LLM provider answers at all; any
+// HTTP response counts, since only the network path is being checked. The
+// result is cached for health.llm_check_interval. It returns nil when no
+// provider is configured.
+func LLMReachable(ctx context.Context) error {
+	if !llmConfigured() {
+		return nil
+	}
+	llmProbeMu.Lock()
+	defer llmProbeMu.Unlock()
+	if !llmProbeAt.IsZero() && time.Since(llmProbeAt) < config.Get().Health.LLMCheckInterval.D() {
+		return llmProbeErr
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, config.Get().LLM.APIURL, nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = llmHTTPClient().Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+	llmProbeAt, llmProbeErr = time.Now(), err
+	return err
+}
+
+// llmChat sends a single user message to the chat completions endpoint and
+// returns the trimmed reply
+func llmChat(ctx context.Context, prompt string) (reply string, err error) {
+	cfg := config.Get().LLM
+	model := cfg.Model
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	ctx, span := tracing.Start(ctx, "llm.chat", attribute.String("llm.model", model))
+	start := time.Now()
+	var usage llmUsage
+	defer func() {
+		metrics.LLMCall("chat", model, time.Since(start), usage.PromptTokens, usage.CompletionTokens, err)
+		span.SetAttributes(attribute.Int("llm.prompt_tokens", usage.PromptTokens), attribute.Int("llm.completion_tokens", usage.CompletionTokens))
+		tracing.End(span, err)
+	}()
+	body, err := json.Marshal(map[string]interface{}{
+		"model":       model,
+		"temperature": 0,
+		"messages":    []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := cfg.APIKey; key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := llmHTTPClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm provider returned %s", resp.Status)
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage llmUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	usage = out.Usage
+	if len(out.Choices) == 0 {
+		return "", errors.New("llm provider returned no choices")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
+
+// llmUsage is the token accounting OpenAI-compatible providers return
+type llmUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
 // InitializeLLMClient initializes the LLM client with the API key from environment variables
 func InitializeLLMClient(apiKey string) {
 	// In a real implementation, this would initialize the LLM client
 	// For now, we'll just log that it was called
-	log.Println("LLM client initialized")
+	slog.Info("LLM client initialized")
 }
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"news-backend/models"
+)
+
+// weights of the related-articles blend
+const (
+	relatedWeightText     = 0.6
+	relatedWeightCategory = 0.2
+	relatedWeightEntity   = 0.1
+	relatedWeightTime     = 0.1
+
+	// minimum text similarity to be considered related at all
+	relatedMinSimilarity = 0.08
+	// articles this similar are treated as copies of the same story
+	nearDuplicateSimilarity = 0.85
+	// temporal proximity halves every relatedTimeHalfLife
+	relatedTimeHalfLife = 72 * time.Hour
+)
+
+// RelatedItem is a related article with its blended score and raw text similarity
+type RelatedItem struct {
+	Article    models.Article
+	Score      float64
+	Similarity float64
+}
+
+// RelatedArticles finds articles similar to target using TF-IDF cosine
+// similarity over title and description, boosted by shared categories, shared
+// tagged entities and publication proximity. Near-duplicates of the target are excluded.
+func RelatedArticles(target models.Article, articles []models.Article, limit int) []RelatedItem {
+	idx := getTFIDFIndex(articles)
+	tv, ok := idx.vecs[target.ID]
+	if !ok {
+		tv = idx.weigh(termFreqs(target))
+	}
+	targetTitle := normalizeTitle(target.Title)
+	targetEntities := lowerSet(target.Entities)
+
+	items := []RelatedItem{}
+	for _, a := range articles {
+		if a.ID == target.ID || (a.URL != "" && a.URL == target.URL) || normalizeTitle(a.Title) == targetTitle {
+			continue
+		}
+		sim := tv.dot(idx.vecs[a.ID])
+		if sim < relatedMinSimilarity || sim >= nearDuplicateSimilarity {
+			continue
+		}
+		cat := jaccard(lowerSet(target.Category), lowerSet(a.Category))
+		ent := jaccard(targetEntities, lowerSet(a.Entities))
+		near := 0.0
+		if !target.Publication.IsZero() && !a.Publication.IsZero() {
+			dt := math.Abs(target.Publication.Sub(a.Publication).Hours())
+			near = math.Exp(-math.Ln2 * dt / relatedTimeHalfLife.Hours())
+		}
+		score := relatedWeightText*sim + relatedWeightCategory*cat + relatedWeightEntity*ent + relatedWeightTime*near
+		items = append(items, RelatedItem{Article: a, Score: score, Similarity: sim})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Score > items[j].Score
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+func normalizeTitle(t string) string {
+	return strings.Join(Tokenize(t), " ")
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for k := range a {
+		if b[k] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
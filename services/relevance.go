@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/logging"
+	"news-backend/models"
+)
+
+// weights of the computed relevance score; they sum to 1
+const (
+	relevanceWeightEditorial  = 0.30
+	relevanceWeightSource     = 0.15
+	relevanceWeightFreshness  = 0.20
+	relevanceWeightEngagement = 0.15
+	relevanceWeightQuality    = 0.10
+	relevanceWeightCoverage   = 0.10
+
+	// freshness halves every relevanceFreshnessHalfLife
+	relevanceFreshnessHalfLife = 48 * time.Hour
+	// source reliability is the editorial mean of a source, shrunk towards
+	// sourcePrior as if the source had sourcePriorWeight extra articles
+	sourcePrior       = 0.5
+	sourcePriorWeight = 5.0
+	// a story covered by this many articles gets full coverage credit
+	fullCoverageSize = 16
+)
+
+// RelevanceBreakdown is a computed relevance score and its components, each in [0,1]
+type RelevanceBreakdown struct {
+	Score      float64   `json:"score"`
+	Editorial  float64   `json:"editorial"`
+	Source     float64   `json:"source_reliability"`
+	Freshness  float64   `json:"freshness"`
+	Engagement float64   `json:"engagement"`
+	Quality    float64   `json:"quality"`
+	Coverage   float64   `json:"coverage"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+var (
+	relevanceMu sync.RWMutex
+	relevance   = map[string]RelevanceBreakdown{}
+)
+
+// ComputeRelevance scores all articles from their editorial score, source
+// reliability, freshness, engagement from the events stream, content quality
+// and story cluster size, and replaces the stored scores.
+func ComputeRelevance(articles []models.Article) {
+	now := time.Now().UTC()
+	// freshness is measured against the newest article so archived corpora still rank sensibly
+	var newest time.Time
+	sourceSum, sourceCount := map[string]float64{}, map[string]float64{}
+	for _, a := range articles {
+		if p := publicationOf(a); p.After(newest) {
+			newest = p
+		}
+		s := strings.ToLower(a.SourceName)
+		sourceSum[s] += clamp01(a.RelevanceScore)
+		sourceCount[s]++
+	}
+
+	engagement := engagementCounts()
+	maxEngagement := 0.0
+	for _, v := range engagement {
+		maxEngagement = math.Max(maxEngagement, v)
+	}
+
+	scores := make(map[string]RelevanceBreakdown, len(articles))
+	for _, a := range articles {
+		s := strings.ToLower(a.SourceName)
+		b := RelevanceBreakdown{
+			Editorial: clamp01(a.RelevanceScore),
+			Source:    (sourceSum[s] + sourcePrior*sourcePriorWeight) / (sourceCount[s] + sourcePriorWeight),
+			Quality:   contentQuality(a),
+			Coverage:  storyCoverage(a.ID),
+		}
+		if p := publicationOf(a); !p.IsZero() {
+			age := newest.Sub(p)
+			b.Freshness = math.Exp(-math.Ln2 * age.Hours() / relevanceFreshnessHalfLife.Hours())
+		}
+		if maxEngagement > 0 {
+			// log scale so a few viral articles do not flatten everything else
+			b.Engagement = math.Log1p(engagement[a.ID]) / math.Log1p(maxEngagement)
+		}
+		b.Score = relevanceWeightEditorial*b.Editorial +
+			relevanceWeightSource*b.Source +
+			relevanceWeightFreshness*b.Freshness +
+			relevanceWeightEngagement*b.Engagement +
+			relevanceWeightQuality*b.Quality +
+			relevanceWeightCoverage*b.Coverage
+		b.ComputedAt = now
+		scores[a.ID] = b
+	}
+
+	relevanceMu.Lock()
+	relevance = scores
+	relevanceMu.Unlock()
+}
+
+// RelevanceOf returns the computed relevance of an article, falling back to
+// its editorial score before the first computation.
+func RelevanceOf(a models.Article) float64 {
+	relevanceMu.RLock()
+	b, ok := relevance[a.ID]
+	relevanceMu.RUnlock()
+	if !ok {
+		return a.RelevanceScore
+	}
+	return b.Score
+}
+
+// ExplainRelevance returns the score components of an article
+func ExplainRelevance(articleID string) (RelevanceBreakdown, bool) {
+	relevanceMu.RLock()
+	defer relevanceMu.RUnlock()
+	b, ok := relevance[articleID]
+	return b, ok
+}
+
+// StartRelevanceScorer recalculates scores now and then every interval until
+// ctx is done. load supplies the current articles.
+func StartRelevanceScorer(ctx context.Context, interval time.Duration, load func(context.Context) ([]models.Article, error)) {
+	refresh := func() {
+		_ = logging.Run(ctx, "relevance_refresh", func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			articles, err := load(ctx)
+			if err != nil {
+				return err
+			}
+			ComputeRelevance(articles)
+			return nil
+		})
+	}
+	refresh()
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				refresh()
+			}
+		}
+	}()
+}
+
+func publicationOf(a models.Article) time.Time {
+	if !a.Publication.IsZero() {
+		return a.Publication
+	}
+	return models.ParsePublication(a.PublicationRaw)
+}
+
+// engagementCounts weighs retained events per article like trending does,
+// without the location and recency terms
+func engagementCounts() map[string]float64 {
+	weights := map[string]float64{"view": 1.0, "click": 2.0, "share": 3.0}
+	out := map[string]float64{}
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+	for _, e := range events {
+		out[e.ArticleID] += weights[e.Type]
+	}
+	return out
+}
+
+// contentQuality rewards a substantive description, a reasonable headline,
+// a link and location data, and penalises shouting headlines
+func contentQuality(a models.Article) float64 {
+	q := 0.0
+	if words := len(strings.Fields(a.Description)); words > 0 {
+		q += 0.4 * math.Min(1, float64(words)/40)
+	}
+	if n := len(strings.Fields(a.Title)); n >= 4 && n <= 20 {
+		q += 0.2
+	}
+	if strings.HasPrefix(a.URL, "http") {
+		q += 0.2
+	}
+	if a.Latitude != 0 || a.Longitude != 0 {
+		q += 0.1
+	}
+	if a.Title != "" && a.Title != strings.ToUpper(a.Title) {
+		q += 0.1
+	}
+	return q
+}
+
+// storyCoverage grows with the number of articles covering the same story
+func storyCoverage(articleID string) float64 {
+	id, ok := StoryIDFor(articleID)
+	if !ok {
+		return 0
+	}
+	s, ok := GetStory(id)
+	if !ok || len(s.Members) < 2 {
+		return 0
+	}
+	return math.Min(1, math.Log2(float64(len(s.Members)))/math.Log2(fullCoverageSize))
+}
+
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultSeedBatch is the number of articles per bulk upsert when seeding
+	DefaultSeedBatch = 1000
+	// time allowed for writing one batch
+	seedBatchTimeout = time.Minute
+	// skipped records logged individually before only counting them
+	seedLoggedSkips = 50
+	// records between progress log lines
+	seedProgressEvery = 50000
+)
+
+// seedCheckpoint records how far seeding from a file got, so an interrupted
+// run resumes instead of starting over. A file whose size or modification
+// time changed is seeded from the start again.
+type seedCheckpoint struct {
+	File      string    `bson:"_id"`
+	Size      int64     `bson:"size"`
+	ModTime   time.Time `bson:"mod_time"`
+	Records   int       `bson:"records"` // input records consumed, valid or not
+	Done      bool      `bson:"done"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// SeedReport summarises a seeding run
+type SeedReport struct {
+	Read     int            `json:"read"`
+	Resumed  int            `json:"resumed"` // records skipped because an earlier run stored them
+	Skipped  int            `json:"skipped"`
+	Inserted int64          `json:"inserted"`
+	Updated  int64          `json:"updated"`
+	Reasons  map[string]int `json:"reasons,omitempty"` // skip reason -> count
+	UpToDate bool           `json:"up_to_date"`        // the file was already fully seeded
+}
+
+func seedColl() *mongo.Collection {
+	return articlesColl.Database().Collection("seed_progress")
+}
+
+// SeedArticles streams articles from a JSON or JSON Lines file into the
+// articles collection. Each record is validated; invalid ones are skipped
+// and their reasons reported. Valid ones get a language and entity tags and
+// are upserted by ID in batches, after which a checkpoint is saved. New
+// articles are pushed to live feed subscribers.
+func SeedArticles(ctx context.Context, path string, batchSize int) (SeedReport, error) {
+	report := SeedReport{Reasons: map[string]int{}}
+	if batchSize < 1 {
+		batchSize = DefaultSeedBatch
+	}
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return report, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return report, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return report, err
+	}
+
+	cp := seedCheckpoint{File: path}
+	err = seedColl().FindOne(ctx, bson.M{"_id": path}).Decode(&cp)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return report, err
+	}
+	if cp.Size != info.Size() || !cp.ModTime.Equal(info.ModTime().UTC().Truncate(time.Millisecond)) {
+		cp = seedCheckpoint{File: path, Size: info.Size(), ModTime: info.ModTime().UTC().Truncate(time.Millisecond)}
+	}
+	if cp.Done {
+		report.UpToDate = true
+		return report, nil
+	}
+
+	reader, err := NewArticleReader(f, format)
+	if err != nil {
+		return report, err
+	}
+	skip := func(e *RecordError) {
+		report.Skipped++
+		for _, r := range e.Errors {
+			report.Reasons[r]++
+		}
+		if report.Skipped <= seedLoggedSkips {
+			slog.WarnContext(ctx, "seed: skipping record", "file", path, "record", e.Record, "id", e.ID, "problems", e.Errors)
+		}
+	}
+	batch := []models.Article{}
+	lastLogged := 0
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := seedBatch(ctx, batch, &report); err != nil {
+				return err
+			}
+		}
+		if report.Read-lastLogged >= seedProgressEvery {
+			slog.InfoContext(ctx, "seed progress", "file", path, "read", report.Read, "inserted", report.Inserted, "skipped", report.Skipped)
+			lastLogged = report.Read
+		}
+		batch = batch[:0]
+		cp.Records = report.Resumed + report.Read
+		return saveCheckpoint(ctx, cp)
+	}
+
+	for {
+		a, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		// fast-forward over records an earlier run already consumed
+		if report.Resumed < cp.Records {
+			if _, ok := err.(*RecordError); ok || err == nil {
+				report.Resumed++
+				continue
+			}
+		}
+		if rerr, ok := err.(*RecordError); ok {
+			report.Read++
+			skip(rerr)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("seed %s after record %d: %w", path, report.Resumed+report.Read, err)
+		}
+		report.Read++
+		if problems := ValidateArticle(a); len(problems) > 0 {
+			skip(&RecordError{Record: report.Resumed + report.Read, ID: a.ID, URL: a.URL, Errors: problems})
+			continue
+		}
+		PrepareForImport(&a)
+		batch = append(batch, a)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	cp.Done = true
+	return report, saveCheckpoint(ctx, cp)
+}
+
+// seedBatch enriches and upserts one batch
+func seedBatch(ctx context.Context, batch []models.Article, report *SeedReport) error {
+	bctx, cancel := context.WithTimeout(ctx, seedBatchTimeout)
+	defer cancel()
+	// a repeated ID within a batch could be inserted twice by unordered upserts
+	seen := make(map[string]bool, len(batch))
+	unique := batch[:0]
+	for _, a := range batch {
+		if seen[a.ID] {
+			report.Skipped++
+			report.Reasons["duplicate id in batch"]++
+			continue
+		}
+		seen[a.ID] = true
+		unique = append(unique, a)
+	}
+	res, err := UpsertArticles(bctx, unique, MatchByID)
+	report.Inserted += res.Inserted
+	report.Updated += res.Updated
+	return err
+}
+
+func saveCheckpoint(ctx context.Context, cp seedCheckpoint) error {
+	cp.UpdatedAt = time.Now().UTC()
+	_, err := seedColl().ReplaceOne(ctx, bson.M{"_id": cp.File}, cp, options.Replace().SetUpsert(true))
+	return err
+}
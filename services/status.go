@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"news-backend/models"
+)
+
+// Startup tasks whose progress readiness checks report
+const (
+	TaskMigrations = "migrations"
+	TaskSeed       = "seed"
+	TaskTrending   = "trending"
+	TaskIndex      = "index"
+)
+
+// Task states
+const (
+	TaskPending = "pending"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+	TaskSkipped = "skipped"
+)
+
+// TaskState is the last reported state of a startup task
+type TaskState struct {
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+var (
+	tasksMu sync.RWMutex
+	tasks   = map[string]TaskState{
+		TaskMigrations: {Status: TaskPending, Since: time.Now()},
+		TaskSeed:       {Status: TaskPending, Since: time.Now()},
+		TaskTrending:   {Status: TaskPending, Since: time.Now()},
+		TaskIndex:      {Status: TaskPending, Since: time.Now()},
+	}
+)
+
+// SetTaskState records a task's progress; err is only kept for TaskFailed
+func SetTaskState(task, status string, err error) {
+	s := TaskState{Status: status, Since: time.Now()}
+	if err != nil && status == TaskFailed {
+		s.Error = err.Error()
+	}
+	tasksMu.Lock()
+	tasks[task] = s
+	tasksMu.Unlock()
+}
+
+// Task returns a task's state
+func Task(task string) TaskState {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	return tasks[task]
+}
+
+// TaskNames lists the tracked tasks in a stable order
+func TaskNames() []string {
+	tasksMu.RLock()
+	defer tasksMu.RUnlock()
+	out := make([]string, 0, len(tasks))
+	for name := range tasks {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ErrDatabaseUnavailable is returned for work that needs MongoDB while it is
+// unreachable
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+var databaseUp atomic.Bool
+
+// SetDatabaseAvailable records whether MongoDB is reachable
+func SetDatabaseAvailable(up bool) {
+	databaseUp.Store(up)
+}
+
+// DatabaseAvailable reports whether MongoDB was reachable at the last check.
+// While it is not, the service runs read-only from the article snapshot.
+func DatabaseAvailable() bool {
+	return databaseUp.Load()
+}
+
+// SnapshotInfo describes the in-memory article snapshot
+type SnapshotInfo struct {
+	Articles int       `json:"articles"`
+	Source   string    `json:"source"` // "database" or the seed file path
+	TakenAt  time.Time `json:"taken_at"`
+}
+
+var (
+	snapshotMu   sync.RWMutex
+	snapshot     []models.Article
+	snapshotInfo SnapshotInfo
+)
+
+// SetArticleSnapshot replaces the copy of the articles served while the
+// database is down. The slice must not be modified afterwards.
+func SetArticleSnapshot(articles []models.Article, source string) {
+	snapshotMu.Lock()
+	defer snapshotMu.Unlock()
+	snapshot = articles
+	snapshotInfo = SnapshotInfo{Articles: len(articles), Source: source, TakenAt: time.Now()}
+}
+
+// ArticleSnapshot returns the snapshot, which callers must not modify
+func ArticleSnapshot() ([]models.Article, SnapshotInfo) {
+	snapshotMu.RLock()
+	defer snapshotMu.RUnlock()
+	return snapshot, snapshotInfo
+}
+
+// EventCount returns how many trending events are held in memory
+func EventCount() int {
+	eventsMu.RLock()
+	defer eventsMu.RUnlock()
+	return len(events)
+}
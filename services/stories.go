@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// minimum cosine similarity between an article and a story centroid to join it
+	storyJoinSimilarity = 0.5
+	// an article only joins stories seen within this window of its publication
+	storyWindow = 72 * time.Hour
+
+	storiesCollection = "stories"
+)
+
+var (
+	storiesColl *mongo.Collection
+
+	// clusterMu serialises ClusterArticles runs; storiesMu guards the maps
+	clusterMu sync.Mutex
+	storiesMu sync.RWMutex
+	stories   = map[string]*models.Story{}
+	// article ID -> story ID
+	storyOf = map[string]string{}
+)
+
+// InitStories wires the stories collection and loads existing stories into memory.
+func InitStories(client *mongo.Client, dbName string) {
+	storiesColl = client.Database(dbName).Collection(storiesCollection)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := storiesColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "last_seen", Value: -1}}},
+		{Keys: bson.D{{Key: "members", Value: 1}}},
+	}); err != nil {
+		slog.ErrorContext(ctx, "stories indexes", "error", err)
+	}
+	cur, err := storiesColl.Find(ctx, bson.M{})
+	if err != nil {
+		slog.ErrorContext(ctx, "load stories", "error", err)
+		return
+	}
+	defer cur.Close(ctx)
+	dirty := []string{}
+	storiesMu.Lock()
+	for cur.Next(ctx) {
+		var s models.Story
+		if err := cur.Decode(&s); err != nil {
+			continue
+		}
+		stories[s.ID] = &s
+		for _, m := range s.Members {
+			storyOf[m] = s.ID
+		}
+		if s.Dirty {
+			dirty = append(dirty, s.ID)
+		}
+	}
+	storiesMu.Unlock()
+	if len(dirty) > 0 {
+		clusterMu.Lock()
+		defer clusterMu.Unlock()
+		if err := rebuildStories(ctx, dirty); err != nil {
+			slog.ErrorContext(ctx, "rebuild stories", "error", err)
+		}
+	}
+}
+
+// ClusterArticles assigns articles not yet in a story to the closest story
+// (by embedding similarity to its centroid) or starts a new one, then persists
+// the stories that changed. Articles are processed in publication order so
+// stories grow the way they would have online.
+func ClusterArticles(ctx context.Context, articles []models.Article) error {
+	clusterMu.Lock()
+	defer clusterMu.Unlock()
+	storiesMu.RLock()
+	pending := []models.Article{}
+	for _, a := range articles {
+		if _, ok := storyOf[a.ID]; !ok {
+			pending = append(pending, a)
+		}
+	}
+	storiesMu.RUnlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	for i := range pending {
+		if pending[i].Publication.IsZero() {
+			pending[i].Publication = models.ParsePublication(pending[i].PublicationRaw)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Publication.Before(pending[j].Publication)
+	})
+	vecs, err := EnsureEmbeddings(ctx, pending)
+	if err != nil {
+		return err
+	}
+
+	storiesMu.Lock()
+	touched := map[string]bool{}
+	for _, a := range pending {
+		v := vecs[a.ID]
+		if len(v) == 0 {
+			continue
+		}
+		best, bestSim := "", storyJoinSimilarity
+		for id, s := range stories {
+			if len(s.Centroid) != len(v) || !withinWindow(a.Publication, s) {
+				continue
+			}
+			if sim := cosine(s.Centroid, v); sim >= bestSim {
+				best, bestSim = id, sim
+			}
+		}
+		if best == "" {
+			id, err := randomHex(8)
+			if err != nil {
+				storiesMu.Unlock()
+				return err
+			}
+			s := &models.Story{ID: id, FirstSeen: a.Publication, LastSeen: a.Publication}
+			stories[id] = s
+			best = id
+		}
+		addToStory(stories[best], a, v)
+		storyOf[a.ID] = best
+		touched[best] = true
+	}
+	// snapshot changed stories so persisting does not hold the lock
+	titles := make(map[string]string, len(pending))
+	for _, a := range pending {
+		titles[a.ID] = a.Title
+	}
+	changed := make([]models.Story, 0, len(touched))
+	for id := range touched {
+		s := stories[id]
+		pickRepresentative(s, vecs, titles)
+		changed = append(changed, *s)
+	}
+	storiesMu.Unlock()
+
+	return saveStories(ctx, changed)
+}
+
+// leaveStories removes articles from their stories so they are clustered
+// again. In coll the stories are marked dirty, for processes that do not hold
+// them (newsctl) to leave the rebuild to the server; stories held in memory
+// are rebuilt at once. Stories left empty are deleted.
+func leaveStories(ctx context.Context, coll *mongo.Collection, ids []string) error {
+	clusterMu.Lock()
+	defer clusterMu.Unlock()
+	storiesMu.Lock()
+	touched := []string{}
+	for _, id := range ids {
+		sid, ok := storyOf[id]
+		if !ok {
+			continue
+		}
+		delete(storyOf, id)
+		s := stories[sid]
+		s.Members = slices.DeleteFunc(s.Members, func(m string) bool { return m == id })
+		if len(s.Members) == 0 {
+			delete(stories, sid)
+		} else if !slices.Contains(touched, sid) {
+			touched = append(touched, sid)
+		}
+	}
+	storiesMu.Unlock()
+
+	if _, err := coll.UpdateMany(ctx, bson.M{"members": bson.M{"$in": ids}}, bson.M{
+		"$pull": bson.M{"members": bson.M{"$in": ids}},
+		"$set":  bson.M{"dirty": true},
+	}); err != nil {
+		return err
+	}
+	if _, err := coll.DeleteMany(ctx, bson.M{"members": bson.M{"$size": 0}}); err != nil {
+		return err
+	}
+	return rebuildStories(ctx, touched)
+}
+
+// rebuildStories recomputes the centroid, representative, headline, sources,
+// categories and dates of loaded stories from their current members, and
+// persists them clean. Members that are no longer stored or have no vector are
+// dropped to be clustered again. The caller holds clusterMu.
+func rebuildStories(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	storiesMu.RLock()
+	memberIDs := []string{}
+	for _, id := range ids {
+		if s, ok := stories[id]; ok {
+			memberIDs = append(memberIDs, s.Members...)
+		}
+	}
+	storiesMu.RUnlock()
+	members, err := FindArticlesByIDs(ctx, memberIDs)
+	if err != nil {
+		return err
+	}
+	vecs, err := EnsureEmbeddings(ctx, members)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]models.Article, len(members))
+	titles := make(map[string]string, len(members))
+	for _, a := range members {
+		byID[a.ID] = a
+		titles[a.ID] = a.Title
+	}
+
+	storiesMu.Lock()
+	changed, emptied := []models.Story{}, []string{}
+	for _, id := range ids {
+		s, ok := stories[id]
+		if !ok {
+			continue
+		}
+		old := s.Members
+		*s = models.Story{ID: s.ID}
+		for _, m := range old {
+			a, ok := byID[m]
+			if !ok || len(vecs[m]) == 0 {
+				delete(storyOf, m)
+				continue
+			}
+			addToStory(s, a, vecs[m])
+		}
+		if len(s.Members) == 0 {
+			delete(stories, id)
+			emptied = append(emptied, id)
+			continue
+		}
+		pickRepresentative(s, vecs, titles)
+		changed = append(changed, *s)
+	}
+	storiesMu.Unlock()
+
+	if len(emptied) > 0 && storiesColl != nil {
+		if _, err := storiesColl.DeleteMany(ctx, bson.M{"id": bson.M{"$in": emptied}}); err != nil {
+			return err
+		}
+	}
+	return saveStories(ctx, changed)
+}
+
+func withinWindow(t time.Time, s *models.Story) bool {
+	if t.IsZero() || s.LastSeen.IsZero() {
+		return true
+	}
+	return !t.Before(s.FirstSeen.Add(-storyWindow)) && !t.After(s.LastSeen.Add(storyWindow))
+}
+
+// addToStory appends a member and moves the centroid to the new mean
+func addToStory(s *models.Story, a models.Article, v []float32) {
+	n := float32(len(s.Members))
+	if len(s.Centroid) == 0 {
+		s.Centroid = make([]float32, len(v))
+	}
+	for i := range v {
+		s.Centroid[i] = (s.Centroid[i]*n + v[i]) / (n + 1)
+	}
+	s.Members = append(s.Members, a.ID)
+	if !a.Publication.IsZero() {
+		if s.FirstSeen.IsZero() || a.Publication.Before(s.FirstSeen) {
+			s.FirstSeen = a.Publication
+		}
+		if a.Publication.After(s.LastSeen) {
+			s.LastSeen = a.Publication
+		}
+	}
+	if a.SourceName != "" && !containsFold(s.Sources, []string{a.SourceName}) {
+		s.Sources = append(s.Sources, a.SourceName)
+	}
+	s.SourceCount = len(s.Sources)
+	for _, c := range a.Category {
+		if !containsFold(s.Categories, []string{c}) {
+			s.Categories = append(s.Categories, c)
+		}
+	}
+}
+
+// pickRepresentative chooses the member closest to the centroid and uses its
+// title as headline. Titles missing from titles are looked up in the article
+// snapshot; if none is found the representative is left unchanged so it never
+// drifts away from the headline.
+func pickRepresentative(s *models.Story, vecs map[string][]float32, titles map[string]string) {
+	best, bestSim := "", math.Inf(-1)
+	for _, id := range s.Members {
+		v, ok := vecs[id]
+		if !ok {
+			v = storedVector(id)
+		}
+		if len(v) != len(s.Centroid) {
+			continue
+		}
+		if sim := cosine(s.Centroid, v); sim > bestSim {
+			best, bestSim = id, sim
+		}
+	}
+	if best == "" || best == s.RepresentativeID {
+		return
+	}
+	t, ok := titles[best]
+	if !ok {
+		t, ok = snapshotTitle(best)
+	}
+	if ok {
+		s.RepresentativeID, s.Headline = best, t
+	}
+}
+
+func snapshotTitle(id string) (string, bool) {
+	articles, _ := ArticleSnapshot()
+	for _, a := range articles {
+		if a.ID == id {
+			return a.Title, true
+		}
+	}
+	return "", false
+}
+
+func cosine(a, b []float32) float64 {
+	dot, na, nb := 0.0, 0.0, 0.0
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func saveStories(ctx context.Context, changed []models.Story) error {
+	if storiesColl == nil || len(changed) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, len(changed))
+	for i, s := range changed {
+		writes[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"id": s.ID}).SetReplacement(s).SetUpsert(true)
+	}
+	_, err := storiesColl.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// ListStories returns stories with at least minSize members, most recently active first
+func ListStories(minSize, offset, limit int, category string) ([]models.Story, int) {
+	storiesMu.RLock()
+	all := []models.Story{}
+	for _, s := range stories {
+		if len(s.Members) < minSize {
+			continue
+		}
+		if category != "" && !containsFold(s.Categories, []string{category}) {
+			continue
+		}
+		all = append(all, *s)
+	}
+	storiesMu.RUnlock()
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].LastSeen.Equal(all[j].LastSeen) {
+			return len(all[i].Members) > len(all[j].Members)
+		}
+		return all[i].LastSeen.After(all[j].LastSeen)
+	})
+	total := len(all)
+	if offset >= total {
+		return []models.Story{}, total
+	}
+	return all[offset:min(offset+limit, total)], total
+}
+
+// GetStory returns a single story by ID
+func GetStory(id string) (models.Story, bool) {
+	storiesMu.RLock()
+	defer storiesMu.RUnlock()
+	s, ok := stories[strings.TrimSpace(id)]
+	if !ok {
+		return models.Story{}, false
+	}
+	return *s, true
+}
+
+// StoryIDFor returns the story an article belongs to, if any
+func StoryIDFor(articleID string) (string, bool) {
+	storiesMu.RLock()
+	defer storiesMu.RUnlock()
+	id, ok := storyOf[articleID]
+	return id, ok
+}
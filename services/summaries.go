@@ -0,0 +1,128 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"news-backend/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// translated summaries kept, least recently used dropped first
+	summaryCacheSize = 10000
+	// translations waiting for the LLM; requests beyond this are dropped and
+	// asked for again by a later response
+	summaryQueueSize = 256
+	summaryTimeout   = time.Minute
+)
+
+// summaryJob asks for the summary of an article in lang
+type summaryJob struct {
+	articleID, title, description, lang string
+}
+
+func (j summaryJob) key() string {
+	return j.articleID + "|" + j.lang
+}
+
+var (
+	summaryMu      sync.Mutex
+	summaryLRU     = list.New() // of *summaryEntry, most recent first
+	summaryEntries = map[string]*list.Element{}
+	summaryPending = map[string]bool{}
+	summaryQueue   = make(chan summaryJob, summaryQueueSize)
+	summaryOnce    sync.Once
+)
+
+type summaryEntry struct {
+	key, summary string
+}
+
+// CachedSummary returns the summary of an article in lang if one was
+// translated already. Otherwise, when an LLM provider is configured, it queues
+// the translation and returns false; readers never wait for the LLM, and each
+// article and language is translated once while cached.
+func CachedSummary(articleID, title, description, lang string) (string, bool) {
+	job := summaryJob{articleID: articleID, title: title, description: description, lang: lang}
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	if el, ok := summaryEntries[job.key()]; ok {
+		summaryLRU.MoveToFront(el)
+		return el.Value.(*summaryEntry).summary, true
+	}
+	if !llmConfigured() || summaryPending[job.key()] {
+		return "", false
+	}
+	summaryOnce.Do(func() { go translateSummaries() })
+	select {
+	case summaryQueue <- job:
+		summaryPending[job.key()] = true
+	default:
+	}
+	return "", false
+}
+
+// translateSummaries fills the cache from the queue, one LLM call at a time
+func translateSummaries() {
+	for job := range summaryQueue {
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		s, err := translateSummary(ctx, job)
+		cancel()
+		if err != nil {
+			slog.Error("llm summary", "article_id", job.articleID, "lang", job.lang, "error", err)
+		}
+		summaryMu.Lock()
+		delete(summaryPending, job.key())
+		if err == nil && s != "" {
+			storeSummary(job.key(), s)
+		}
+		summaryMu.Unlock()
+	}
+}
+
+func translateSummary(ctx context.Context, job summaryJob) (string, error) {
+	ctx, span := tracing.Start(ctx, "llm.translated_summary", attribute.String("article.id", job.articleID), attribute.String("summary.lang", job.lang))
+	defer span.End()
+	prompt := fmt.Sprintf("Summarise this news article in one or two sentences, written in the language with ISO 639-1 code %q.\n\nTitle: %s\nDescription: %s",
+		job.lang, job.title, job.description)
+	return llmChat(ctx, prompt)
+}
+
+// storeSummary caches a summary, evicting the least recently used; the caller
+// holds summaryMu
+func storeSummary(key, s string) {
+	if el, ok := summaryEntries[key]; ok {
+		el.Value.(*summaryEntry).summary = s
+		summaryLRU.MoveToFront(el)
+		return
+	}
+	summaryEntries[key] = summaryLRU.PushFront(&summaryEntry{key: key, summary: s})
+	for summaryLRU.Len() > summaryCacheSize {
+		oldest := summaryLRU.Back()
+		summaryLRU.Remove(oldest)
+		delete(summaryEntries, oldest.Value.(*summaryEntry).key)
+	}
+}
+
+// forgetSummaries drops the cached summaries of rewritten articles
+func forgetSummaries(ids []string) {
+	gone := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	for key, el := range summaryEntries {
+		if id, _, _ := strings.Cut(key, "|"); gone[id] {
+			summaryLRU.Remove(el)
+			delete(summaryEntries, key)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"news-backend/config"
+	"news-backend/models"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "of": true,
+	"to": true, "in": true, "on": true, "at": true, "for": true, "with": true, "by": true,
+	"from": true, "as": true, "is": true, "are": true, "was": true, "were": true, "be": true,
+	"been": true, "has": true, "have": true, "had": true, "it": true, "its": true, "this": true,
+	"that": true, "these": true, "those": true, "he": true, "she": true, "they": true, "his": true,
+	"her": true, "their": true, "we": true, "you": true, "i": true, "not": true, "will": true,
+	"would": true, "can": true, "could": true, "said": true, "says": true, "after": true,
+	"over": true, "into": true, "about": true, "than": true, "also": true, "who": true,
+	"what": true, "which": true, "when": true, "where": true, "how": true, "all": true,
+	"more": true, "new": true, "up": true, "out": true, "no": true, "s": true,
+}
+
+// stop words of other languages our sources publish in, keyed by ISO 639-1 code
+var langStopWords = map[string]map[string]bool{
+	"hi": wordSet("का की के को में से पर और है हैं था थी थे ने यह वह इस उस एक भी तो कि जो लिए ही या हो गया गई किया कर रहे रहा साथ बाद अब तक"),
+	"mr": wordSet("आहे आहेत आणि या ते ती हे की व ने ला ना चा ची चे मध्ये नाही होते केली झाली त्या त्यांनी एक पण तर"),
+}
+
+func wordSet(words string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		out[w] = true
+	}
+	return out
+}
+
+// zero-width joiners are part of words in Indic scripts but not letters
+var zeroWidth = strings.NewReplacer("\u200c", "", "\u200d", "")
+
+// tokenWords splits NFC-normalised, lowercased text into words. Combining
+// marks count as word characters so Indic vowel signs do not split words.
+func tokenWords(text string) []string {
+	text = zeroWidth.Replace(norm.NFC.String(strings.ToLower(text)))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
+}
+
+// Tokenize splits text into words, dropping single characters and the stop
+// words of the text's detected language
+func Tokenize(text string) []string {
+	return TokenizeLang(text, DetectLanguage(text))
+}
+
+// TokenizeLang is Tokenize for text in a known language. English stop words
+// are always dropped since English names and phrases appear in all our sources.
+func TokenizeLang(text, lang string) []string {
+	extra := langStopWords[lang]
+	fields := tokenWords(text)
+	out := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || stopWords[f] || extra[f] {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// sparseVec is an L2-normalised term vector
+type sparseVec map[string]float64
+
+func (v sparseVec) dot(o sparseVec) float64 {
+	if len(o) < len(v) {
+		v, o = o, v
+	}
+	s := 0.0
+	for t, w := range v {
+		s += w * o[t]
+	}
+	return s
+}
+
+// tfidfIndex holds TF-IDF vectors over article title+description
+type tfidfIndex struct {
+	vecs    map[string]sparseVec
+	idf     map[string]float64
+	corpus  uint64 // corpusHash of the indexed articles
+	builtAt time.Time
+}
+
+var (
+	tfidfMu     sync.Mutex
+	tfidfCached *tfidfIndex
+)
+
+// getTFIDFIndex returns a cached index, rebuilding it when an article was
+// added, removed or rewritten, or the index is stale
+func getTFIDFIndex(articles []models.Article) *tfidfIndex {
+	corpus := corpusHash(articles)
+	tfidfMu.Lock()
+	defer tfidfMu.Unlock()
+	if tfidfCached != nil && tfidfCached.corpus == corpus && time.Since(tfidfCached.builtAt) < config.Get().Cache.TFIDFRebuildAfter.D() {
+		return tfidfCached
+	}
+	tfidfCached = buildTFIDF(articles)
+	tfidfCached.corpus = corpus
+	return tfidfCached
+}
+
+// corpusHash fingerprints the indexed text of articles, in any order
+func corpusHash(articles []models.Article) uint64 {
+	var sum uint64
+	for _, a := range articles {
+		h := fnv.New64a()
+		h.Write([]byte(a.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(a.Title))
+		h.Write([]byte{0})
+		h.Write([]byte(a.Description))
+		sum += h.Sum64()
+	}
+	return sum
+}
+
+func buildTFIDF(articles []models.Article) *tfidfIndex {
+	idx := &tfidfIndex{vecs: map[string]sparseVec{}, idf: map[string]float64{}, builtAt: time.Now()}
+	tfs := make(map[string]map[string]float64, len(articles))
+	df := map[string]int{}
+	for _, a := range articles {
+		tf := termFreqs(a)
+		for t := range tf {
+			df[t]++
+		}
+		tfs[a.ID] = tf
+	}
+	n := float64(len(articles))
+	for t, d := range df {
+		idx.idf[t] = math.Log((1+n)/(1+float64(d))) + 1
+	}
+	for id, tf := range tfs {
+		idx.vecs[id] = idx.weigh(tf)
+	}
+	return idx
+}
+
+// termFreqs counts the terms of an article's title and description
+func termFreqs(a models.Article) map[string]float64 {
+	tf := map[string]float64{}
+	// title terms count double, they carry the story
+	for _, t := range Tokenize(a.Title) {
+		tf[t] += 2
+	}
+	for _, t := range Tokenize(a.Description) {
+		tf[t]++
+	}
+	return tf
+}
+
+// weigh turns raw term frequencies into a normalised TF-IDF vector
+func (idx *tfidfIndex) weigh(tf map[string]float64) sparseVec {
+	v := sparseVec{}
+	norm := 0.0
+	for t, f := range tf {
+		idf, ok := idx.idf[t]
+		if !ok {
+			continue
+		}
+		w := (1 + math.Log(f)) * idf
+		v[t] = w
+		norm += w * w
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for t := range v {
+		v[t] /= norm
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"news-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	usersColl     *mongo.Collection
+	historyColl   *mongo.Collection
+	bookmarksColl *mongo.Collection
+)
+
+// InitUsers wires the users, reading history and bookmark collections and ensures their indexes.
+func InitUsers(client *mongo.Client, dbName string) {
+	db := client.Database(dbName)
+	usersColl = db.Collection("users")
+	historyColl = db.Collection("reading_history")
+	bookmarksColl = db.Collection("bookmarks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := usersColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		slog.ErrorContext(ctx, "users indexes", "error", err)
+	}
+	// unique per user+article for upserts, plus newest-first listing per user
+	perUser := func(tsField string) []mongo.IndexModel {
+		return []mongo.IndexModel{
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "article_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: tsField, Value: -1}}},
+		}
+	}
+	if _, err := historyColl.Indexes().CreateMany(ctx, perUser("read_at")); err != nil {
+		slog.ErrorContext(ctx, "reading history indexes", "error", err)
+	}
+	if _, err := bookmarksColl.Indexes().CreateMany(ctx, perUser("created_at")); err != nil {
+		slog.ErrorContext(ctx, "bookmarks indexes", "error", err)
+	}
+}
+
+// GetUser returns the stored preferences for id, or an empty user when none exist yet
+func GetUser(ctx context.Context, id string) (models.User, error) {
+	var u models.User
+	err := usersColl.FindOne(ctx, bson.M{"id": id}).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return models.User{
+			ID:                  id,
+			PreferredCategories: []string{},
+			FollowedSources:     []string{},
+			MutedSources:        []string{},
+		}, nil
+	}
+	return u, err
+}
+
+// SaveUser creates or replaces the preferences for u.ID
+func SaveUser(ctx context.Context, u models.User) (models.User, error) {
+	now := time.Now().UTC()
+	u.UpdatedAt = now
+	update := bson.M{
+		"$set": bson.M{
+			"preferred_categories": nonNil(u.PreferredCategories),
+			"followed_sources":     nonNil(u.FollowedSources),
+			"muted_sources":        nonNil(u.MutedSources),
+			"home_latitude":        u.HomeLatitude,
+			"home_longitude":       u.HomeLongitude,
+			"language":             u.Language,
+			"updated_at":           now,
+		},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var out models.User
+	err := usersColl.FindOneAndUpdate(ctx, bson.M{"id": u.ID}, update, opts).Decode(&out)
+	return out, err
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// ReadArticleIDs returns the set of articles the user has already read
+func ReadArticleIDs(ctx context.Context, userID string) (map[string]bool, error) {
+	cur, err := historyColl.Find(ctx, bson.M{"user_id": userID},
+		options.Find().SetProjection(bson.M{"article_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	out := map[string]bool{}
+	for cur.Next(ctx) {
+		var r struct {
+			ArticleID string `bson:"article_id"`
+		}
+		if err := cur.Decode(&r); err == nil {
+			out[r.ArticleID] = true
+		}
+	}
+	return out, cur.Err()
+}